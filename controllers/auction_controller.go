@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"gin-fleamarket/dto"
+	"gin-fleamarket/models"
+	"gin-fleamarket/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type IAuctionController interface {
+	PlaceBid(ctx *gin.Context)
+	FindBids(ctx *gin.Context)
+}
+
+type AuctionController struct {
+	service services.IAuctionService
+}
+
+func NewAuctionController(service services.IAuctionService) IAuctionController {
+	return &AuctionController{service: service}
+}
+
+func (c *AuctionController) PlaceBid(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	userId := user.(*models.User).ID
+
+	itemId, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		return
+	}
+
+	var input dto.PlaceBidInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	bid, err := c.service.PlaceBid(uint(itemId), userId, input)
+	if err != nil {
+		switch err.Error() {
+		case "Item not found", "Auction not found":
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "Cannot bid on own item":
+			ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case "Auction ended", "Bid too low":
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"data": bid})
+}
+
+func (c *AuctionController) FindBids(ctx *gin.Context) {
+	itemId, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		return
+	}
+
+	var query dto.PageQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := c.service.FindBids(uint(itemId), query)
+	if err != nil {
+		if err.Error() == "Auction not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": page.Bids, "total": page.Total})
+}
@@ -1,8 +1,11 @@
 package controllers
 
 import (
+	"errors"
 	"gin-fleamarket/dto"
+	"gin-fleamarket/models"
 	"gin-fleamarket/services"
+	"io"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -10,7 +13,16 @@ import (
 
 type IAuthController interface {
 	Signup(ctx *gin.Context)
+	VerifyEmail(ctx *gin.Context)
+	ResendVerification(ctx *gin.Context)
+	ForgotPassword(ctx *gin.Context)
+	ResetPassword(ctx *gin.Context)
+	ChangePassword(ctx *gin.Context)
 	Login(ctx *gin.Context)
+	Refresh(ctx *gin.Context)
+	Logout(ctx *gin.Context)
+	LogoutAll(ctx *gin.Context)
+	JWKS(ctx *gin.Context)
 }
 
 type AuthController struct {
@@ -36,6 +48,94 @@ func (c *AuthController) Signup(ctx *gin.Context) {
 	ctx.Status(http.StatusCreated)
 }
 
+func (c *AuthController) VerifyEmail(ctx *gin.Context) {
+	var query dto.VerifyEmailQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := c.service.VerifyEmail(query.Token); err != nil {
+		if err.Error() == "Invalid token" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+	ctx.Status(http.StatusOK)
+}
+
+func (c *AuthController) ResendVerification(ctx *gin.Context) {
+	var input dto.ResendVerificationInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := c.service.ResendVerification(input.Email); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+	ctx.Status(http.StatusAccepted)
+}
+
+func (c *AuthController) ForgotPassword(ctx *gin.Context) {
+	var input dto.ForgotPasswordInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := c.service.ForgotPassword(input.Email); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+	ctx.Status(http.StatusAccepted)
+}
+
+func (c *AuthController) ResetPassword(ctx *gin.Context) {
+	var input dto.ResetPasswordInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := c.service.ResetPassword(input.Token, input.Password); err != nil {
+		if err.Error() == "Invalid token" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+func (c *AuthController) ChangePassword(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	var input dto.ChangePasswordInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := c.service.ChangePassword(user.(*models.User).ID, input.CurrentPassword, input.NewPassword); err != nil {
+		if err.Error() == "Invalid password" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
 func (c *AuthController) Login(ctx *gin.Context) {
 	var input dto.LoginInput
 	if err := ctx.ShouldBindJSON(&input); err != nil {
@@ -43,7 +143,7 @@ func (c *AuthController) Login(ctx *gin.Context) {
 		return
 	}
 
-	token, err := c.service.Login(input.Email, input.Password)
+	output, err := c.service.Login(input.Email, input.Password)
 	if err != nil {
 		if err.Error() == "User not found" {
 			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
@@ -52,5 +152,73 @@ func (c *AuthController) Login(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	ctx.JSON(http.StatusOK, gin.H{"token": token})
+	ctx.JSON(http.StatusOK, output)
+}
+
+func (c *AuthController) Refresh(ctx *gin.Context) {
+	var input dto.RefreshInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	output, err := c.service.Refresh(input.RefreshToken)
+	if err != nil {
+		switch err.Error() {
+		case "Invalid refresh token", "Refresh token reused":
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		}
+		return
+	}
+	ctx.JSON(http.StatusOK, output)
+}
+
+func (c *AuthController) Logout(ctx *gin.Context) {
+	token, exists := ctx.Get("token")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	// リフレッシュトークンの失効は任意なので、本文がなくてもよい
+	var input dto.LogoutInput
+	if err := ctx.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := c.service.Logout(token.(string), input.RefreshToken); err != nil {
+		if err.Error() == "Invalid refresh token" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		// jti のないトークンは個別に失効できない
+		if err.Error() == "Invalid token" {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+func (c *AuthController) LogoutAll(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	if err := c.service.LogoutAll(user.(*models.User).ID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+func (c *AuthController) JWKS(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, c.service.JWKS())
 }
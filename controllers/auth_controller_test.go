@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"gin-fleamarket/dto"
 	"gin-fleamarket/infra"
+	"gin-fleamarket/mail"
 	"gin-fleamarket/models"
 	"gin-fleamarket/repositories"
 	"gin-fleamarket/services"
@@ -12,6 +13,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -27,21 +29,23 @@ func setupAuthTest() (*gin.Engine, *gorm.DB) {
 
 	// Setup database
 	db := infra.SetupDB()
-	db.AutoMigrate(&models.User{})
+	db.AutoMigrate(&models.User{}, &models.RefreshToken{}, &models.RevokedToken{}, &models.UserToken{}, &models.OutboxMail{})
 
 	// Clear any existing users
 	db.Exec("DELETE FROM users")
 
 	// Setup router
 	r := gin.Default()
+	tokenKeys, _ := services.SetupTokenKeys()
 	authRepository := repositories.NewAuthRepository(db)
-	authService := services.NewAuthService(authRepository)
+	authService := services.NewAuthService(authRepository, mail.NewOutboxMailer(db), tokenKeys, time.Hour)
 	authController := NewAuthController(authService)
 
 	// Setup routes
 	authGroup := r.Group("/auth")
 	authGroup.POST("/signup", authController.Signup)
 	authGroup.POST("/login", authController.Login)
+	authGroup.POST("/refresh", authController.Refresh)
 
 	return r, db
 }
@@ -66,3 +70,44 @@ func TestSignup(t *testing.T) {
 	// Assert response
 	assert.Equal(t, http.StatusCreated, w.Code)
 }
+
+func TestLoginAndRefresh(t *testing.T) {
+	r, db := setupAuthTest()
+	defer db.Exec("DELETE FROM refresh_tokens")
+
+	post := func(path string, body any) *httptest.ResponseRecorder {
+		reqBody, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", path, bytes.NewBuffer(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post("/auth/signup", dto.SignupInput{Email: "test@example.com", Password: "password123"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// Login returns an access token and a refresh token
+	w = post("/auth/login", dto.LoginInput{Email: "test@example.com", Password: "password123"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var login dto.TokenOutput
+	json.Unmarshal(w.Body.Bytes(), &login)
+	assert.Assert(t, login.Token != "")
+	assert.Assert(t, login.RefreshToken != "")
+
+	// Refresh rotates the refresh token
+	w = post("/auth/refresh", dto.RefreshInput{RefreshToken: login.RefreshToken})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var refreshed dto.TokenOutput
+	json.Unmarshal(w.Body.Bytes(), &refreshed)
+	assert.Assert(t, refreshed.RefreshToken != login.RefreshToken)
+
+	// Replaying the old refresh token revokes the family
+	w = post("/auth/refresh", dto.RefreshInput{RefreshToken: login.RefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = post("/auth/refresh", dto.RefreshInput{RefreshToken: refreshed.RefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = post("/auth/refresh", gin.H{})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
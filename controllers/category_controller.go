@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"gin-fleamarket/dto"
+	"gin-fleamarket/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ICategoryController interface {
+	FindAll(ctx *gin.Context)
+	FindById(ctx *gin.Context)
+	Create(ctx *gin.Context)
+	Update(ctx *gin.Context)
+	Delete(ctx *gin.Context)
+}
+
+type CategoryController struct {
+	service services.ICategoryService
+}
+
+func NewCategoryController(service services.ICategoryService) ICategoryController {
+	return &CategoryController{service: service}
+}
+
+func (c *CategoryController) FindAll(ctx *gin.Context) {
+	categories, err := c.service.FindTree()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": categories})
+}
+
+func (c *CategoryController) FindById(ctx *gin.Context) {
+	categoryId, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		return
+	}
+
+	category, err := c.service.FindById(uint(categoryId))
+	if err != nil {
+		if err.Error() == "Category not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": category})
+}
+
+func (c *CategoryController) Create(ctx *gin.Context) {
+	var input dto.CreateCategoryInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	newCategory, err := c.service.Create(input)
+	if err != nil {
+		writeCategoryError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"data": newCategory})
+}
+
+func (c *CategoryController) Update(ctx *gin.Context) {
+	categoryId, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		return
+	}
+
+	var input dto.UpdateCategoryInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updatedCategory, err := c.service.Update(uint(categoryId), input)
+	if err != nil {
+		writeCategoryError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": updatedCategory})
+}
+
+func (c *CategoryController) Delete(ctx *gin.Context) {
+	categoryId, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		return
+	}
+
+	if err := c.service.Delete(uint(categoryId)); err != nil {
+		writeCategoryError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+func writeCategoryError(ctx *gin.Context, err error) {
+	switch err.Error() {
+	case "Category not found":
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case "Invalid parent category":
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case "Category in use":
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+	}
+}
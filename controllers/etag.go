@@ -0,0 +1,34 @@
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// versionETag はバージョン番号を強いETagの形式にします
+func versionETag(version uint) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// parseIfMatch は If-Match ヘッダーからバージョンを取り出します
+// ヘッダーがない場合は ok に false を返し、"*" の場合はバージョンを確認しないことを表す0を返します
+func parseIfMatch(ctx *gin.Context) (version uint, ok bool, err error) {
+	header := strings.TrimSpace(ctx.GetHeader("If-Match"))
+	if header == "" {
+		return 0, false, nil
+	}
+	if header == "*" {
+		return 0, true, nil
+	}
+
+	tag := strings.TrimPrefix(header, "W/")
+	tag = strings.TrimSuffix(strings.TrimPrefix(tag, `"`), `"`)
+	v, err := strconv.ParseUint(tag, 10, 64)
+	if err != nil || v == 0 {
+		return 0, true, fmt.Errorf("Invalid If-Match header")
+	}
+	return uint(v), true, nil
+}
@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"gin-fleamarket/dto"
+	"gin-fleamarket/models"
+	"gin-fleamarket/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type IItemCommentController interface {
+	FindByItem(ctx *gin.Context)
+	Create(ctx *gin.Context)
+	Delete(ctx *gin.Context)
+}
+
+type ItemCommentController struct {
+	service services.IItemCommentService
+}
+
+func NewItemCommentController(service services.IItemCommentService) IItemCommentController {
+	return &ItemCommentController{service: service}
+}
+
+func (c *ItemCommentController) FindByItem(ctx *gin.Context) {
+	itemId, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		return
+	}
+
+	var query dto.PageQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := c.service.FindByItem(uint(itemId), query)
+	if err != nil {
+		writeItemCommentError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": page.Comments, "total": page.Total})
+}
+
+func (c *ItemCommentController) Create(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	userId := user.(*models.User).ID
+
+	itemId, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		return
+	}
+
+	var input dto.CreateItemCommentInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	comment, err := c.service.Create(uint(itemId), userId, input)
+	if err != nil {
+		writeItemCommentError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"data": comment})
+}
+
+func (c *ItemCommentController) Delete(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	itemId, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		return
+	}
+	commentId, err := strconv.ParseUint(ctx.Param("commentId"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		return
+	}
+
+	if err := c.service.Delete(uint(itemId), uint(commentId), *user.(*models.User)); err != nil {
+		writeItemCommentError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+func writeItemCommentError(ctx *gin.Context, err error) {
+	switch err.Error() {
+	case "Item not found", "Comment not found":
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case "Forbidden":
+		ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case "Comments locked":
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+	}
+}
@@ -12,10 +12,15 @@ import (
 
 type IItemController interface {
 	FindAll(ctx *gin.Context)
+	FindMine(ctx *gin.Context)
 	FindById(ctx *gin.Context)
+	FindPublicById(ctx *gin.Context)
+	Search(ctx *gin.Context)
 	Create(ctx *gin.Context)
 	Update(ctx *gin.Context)
 	Delete(ctx *gin.Context)
+	FindTrash(ctx *gin.Context)
+	Restore(ctx *gin.Context)
 }
 
 type ItemController struct {
@@ -27,13 +32,61 @@ func NewItemController(service services.IItemService) IItemController {
 }
 
 func (c *ItemController) FindAll(ctx *gin.Context) {
-	items, err := c.service.FindAll()
+	var query dto.FindItemsQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := c.service.FindAll(query)
+	if err != nil {
+		if err.Error() == "Invalid cursor" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": page.Items, "next_cursor": page.NextCursor, "total": page.Total})
+}
+
+func (c *ItemController) FindMine(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	userId := user.(*models.User).ID
+
+	var query dto.FindItemsQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := c.service.FindMine(userId, query)
+	if err != nil {
+		if err.Error() == "Invalid cursor" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+	statusCounts, err := c.service.CountMineByStatus(userId)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"data": items})
+	ctx.JSON(http.StatusOK, gin.H{
+		"data":          page.Items,
+		"next_cursor":   page.NextCursor,
+		"total":         page.Total,
+		"status_counts": statusCounts,
+	})
 }
 
 func (c *ItemController) FindById(ctx *gin.Context) {
@@ -63,9 +116,46 @@ func (c *ItemController) FindById(ctx *gin.Context) {
 			return
 		}
 	}
+	ctx.Header("ETag", versionETag(item.Version))
 	ctx.JSON(http.StatusOK, gin.H{"data": item})
 }
 
+func (c *ItemController) FindPublicById(ctx *gin.Context) {
+	itemId, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		return
+	}
+
+	item, err := c.service.FindPublicById(uint(itemId))
+	if err != nil {
+		if err.Error() == "Item not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+	ctx.Header("ETag", versionETag(item.Version))
+	ctx.JSON(http.StatusOK, gin.H{"data": item})
+}
+
+func (c *ItemController) Search(ctx *gin.Context) {
+	var query dto.SearchItemsQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := c.service.Search(query)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": page.Items, "total": page.Total})
+}
+
 func (c *ItemController) Create(ctx *gin.Context) {
 	user, exists := ctx.Get("user")
 	if !exists {
@@ -82,10 +172,15 @@ func (c *ItemController) Create(ctx *gin.Context) {
 	}
 	newItem, err := c.service.Create(input, userId)
 	if err != nil {
+		if err.Error() == "Invalid category" || err.Error() == "Invalid auction" || err.Error() == "Invalid auction end time" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	ctx.Header("ETag", versionETag(newItem.Version))
 	ctx.JSON(http.StatusCreated, gin.H{"data": newItem})
 }
 
@@ -104,23 +199,43 @@ func (c *ItemController) Update(ctx *gin.Context) {
 		return
 	}
 
+	version, ok, err := parseIfMatch(ctx)
+	if !ok {
+		ctx.JSON(http.StatusPreconditionRequired, gin.H{"error": "If-Match header required"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+		return
+	}
+
 	var input dto.UpdateItemInput
 	if err := ctx.ShouldBindJSON(&input); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	updatedItem, err := c.service.Update(uint(itemId), userId, input)
+	updatedItem, err := c.service.Update(uint(itemId), userId, version, input)
 	if err != nil {
 		if err.Error() == "Item not found" {
 			ctx.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
 			return
+		} else if err.Error() == "Version conflict" {
+			ctx.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+			return
+		} else if err.Error() == "Invalid category" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		} else if err.Error() == "Invalid status transition" || err.Error() == "Cannot change auction listing" || err.Error() == "Item in trade" {
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
 		} else {
 			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
 			return
 		}
 	}
 
+	ctx.Header("ETag", versionETag(updatedItem.Version))
 	ctx.JSON(http.StatusOK, gin.H{"data": updatedItem})
 }
 
@@ -139,11 +254,27 @@ func (c *ItemController) Delete(ctx *gin.Context) {
 		return
 	}
 
-	err = c.service.Delete(uint(itemId), userId)
+	version, ok, err := parseIfMatch(ctx)
+	if !ok {
+		ctx.JSON(http.StatusPreconditionRequired, gin.H{"error": "If-Match header required"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = c.service.Delete(uint(itemId), userId, version)
 	if err != nil {
 		if err.Error() == "Item not found" {
 			ctx.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
 			return
+		} else if err.Error() == "Version conflict" {
+			ctx.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+			return
+		} else if err.Error() == "Auction in progress" || err.Error() == "Item in trade" {
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
 		} else {
 			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
 			return
@@ -152,3 +283,56 @@ func (c *ItemController) Delete(ctx *gin.Context) {
 
 	ctx.Status(http.StatusOK)
 }
+
+func (c *ItemController) FindTrash(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	userId := user.(*models.User).ID
+
+	var query dto.PageQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := c.service.FindTrash(userId, query)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": page.Items, "total": page.Total})
+}
+
+func (c *ItemController) Restore(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	userId := user.(*models.User).ID
+
+	itemId, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		return
+	}
+
+	restoredItem, err := c.service.Restore(uint(itemId), userId)
+	if err != nil {
+		if err.Error() == "Item not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+
+	ctx.Header("ETag", versionETag(restoredItem.Version))
+	ctx.JSON(http.StatusOK, gin.H{"data": restoredItem})
+}
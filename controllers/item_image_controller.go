@@ -0,0 +1,181 @@
+package controllers
+
+import (
+	"errors"
+	"gin-fleamarket/dto"
+	"gin-fleamarket/models"
+	"gin-fleamarket/services"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	maxImageFileSize = 10 << 20
+	// maxImageFormOverhead は multipart の境界やヘッダーのために画像本体とは別に許容するサイズです
+	maxImageFormOverhead = 1 << 20
+)
+
+type IItemImageController interface {
+	Upload(ctx *gin.Context)
+	Reorder(ctx *gin.Context)
+	Delete(ctx *gin.Context)
+}
+
+type ItemImageController struct {
+	service services.IItemImageService
+}
+
+func NewItemImageController(service services.IItemImageService) IItemImageController {
+	return &ItemImageController{service: service}
+}
+
+func (c *ItemImageController) Upload(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	userId := user.(*models.User).ID
+
+	itemId, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		return
+	}
+
+	// リクエスト全体をメモリやディスクに読み込まないように、パートを1つずつ読みながら枚数とサイズを確認する
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, services.MaxImagesPerItem*maxImageFileSize+maxImageFormOverhead)
+	reader, err := ctx.Request.MultipartReader()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var contents [][]byte
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			writeImageReadError(ctx, err)
+			return
+		}
+		if part.FormName() != "images" || part.FileName() == "" {
+			part.Close()
+			continue
+		}
+		if len(contents) == services.MaxImagesPerItem {
+			part.Close()
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Too many images"})
+			return
+		}
+		content, err := io.ReadAll(io.LimitReader(part, maxImageFileSize+1))
+		part.Close()
+		if err != nil {
+			writeImageReadError(ctx, err)
+			return
+		}
+		if len(content) > maxImageFileSize {
+			ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Image too large"})
+			return
+		}
+		contents = append(contents, content)
+	}
+	if len(contents) == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "No images"})
+		return
+	}
+
+	images, err := c.service.Upload(uint(itemId), userId, contents)
+	if err != nil {
+		writeItemImageError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"data": images})
+}
+
+func (c *ItemImageController) Reorder(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	userId := user.(*models.User).ID
+
+	itemId, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		return
+	}
+
+	var input dto.ReorderItemImagesInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	images, err := c.service.Reorder(uint(itemId), userId, input.ImageIDs)
+	if err != nil {
+		writeItemImageError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": images})
+}
+
+func (c *ItemImageController) Delete(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	userId := user.(*models.User).ID
+
+	itemId, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		return
+	}
+	imageId, err := strconv.ParseUint(ctx.Param("imageId"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		return
+	}
+
+	if err := c.service.Delete(uint(itemId), userId, uint(imageId)); err != nil {
+		writeItemImageError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+func writeImageReadError(ctx *gin.Context, err error) {
+	var maxBytesError *http.MaxBytesError
+	if errors.As(err, &maxBytesError) {
+		ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request too large"})
+		return
+	}
+	ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+func writeItemImageError(ctx *gin.Context, err error) {
+	switch err.Error() {
+	case "Item not found", "Image not found":
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case "Image too large":
+		ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+	case "Too many images", "Unsupported image format", "Invalid image order":
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+	}
+}
@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"gin-fleamarket/dto"
+	"gin-fleamarket/models"
+	"gin-fleamarket/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type IItemLikeController interface {
+	Like(ctx *gin.Context)
+	Unlike(ctx *gin.Context)
+	FindMine(ctx *gin.Context)
+}
+
+type ItemLikeController struct {
+	service services.IItemLikeService
+}
+
+func NewItemLikeController(service services.IItemLikeService) IItemLikeController {
+	return &ItemLikeController{service: service}
+}
+
+func (c *ItemLikeController) Like(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	userId := user.(*models.User).ID
+
+	itemId, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		return
+	}
+
+	output, err := c.service.Like(uint(itemId), userId)
+	if err != nil {
+		writeItemLikeError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": output})
+}
+
+func (c *ItemLikeController) Unlike(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	userId := user.(*models.User).ID
+
+	itemId, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		return
+	}
+
+	output, err := c.service.Unlike(uint(itemId), userId)
+	if err != nil {
+		writeItemLikeError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": output})
+}
+
+func (c *ItemLikeController) FindMine(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	userId := user.(*models.User).ID
+
+	var query dto.PageQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := c.service.FindMine(userId, query)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": page.Items, "total": page.Total})
+}
+
+func writeItemLikeError(ctx *gin.Context, err error) {
+	if err.Error() == "Item not found" {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+}
@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"gin-fleamarket/dto"
+	"gin-fleamarket/models"
+	"gin-fleamarket/services"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxImportFileSize = 5 << 20
+
+type IItemTransferController interface {
+	Import(ctx *gin.Context)
+	Export(ctx *gin.Context)
+}
+
+type ItemTransferController struct {
+	service services.IItemTransferService
+}
+
+func NewItemTransferController(service services.IItemTransferService) IItemTransferController {
+	return &ItemTransferController{service: service}
+}
+
+// Import は multipart の file フィールド、または text/csv のリクエストボディを受け付けます
+func (c *ItemTransferController) Import(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	userId := user.(*models.User).ID
+
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxImportFileSize)
+
+	var body io.Reader = ctx.Request.Body
+	if strings.HasPrefix(ctx.ContentType(), "multipart/") {
+		fileHeader, err := ctx.FormFile("file")
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	output, err := c.service.Import(userId, body)
+	if err != nil {
+		switch err.Error() {
+		case "Invalid CSV", "Invalid CSV header", "Too many rows":
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": output})
+}
+
+func (c *ItemTransferController) Export(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	userId := user.(*models.User).ID
+
+	var query dto.ExportItemsQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if query.Format == "json" {
+		ctx.Header("Content-Type", "application/json; charset=utf-8")
+		ctx.Header("Content-Disposition", `attachment; filename="items.json"`)
+	} else {
+		ctx.Header("Content-Type", "text/csv; charset=utf-8")
+		ctx.Header("Content-Disposition", `attachment; filename="items.csv"`)
+	}
+	ctx.Status(http.StatusOK)
+
+	// ストリーミング中はステータスコードを変更できないためログに残す
+	if err := c.service.Export(userId, query.Format, ctx.Writer); err != nil {
+		log.Printf("Failed to export items: %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"gin-fleamarket/dto"
+	"gin-fleamarket/models"
+	"gin-fleamarket/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type IMessageController interface {
+	Send(ctx *gin.Context)
+	FindByOrder(ctx *gin.Context)
+	MarkRead(ctx *gin.Context)
+	CountUnread(ctx *gin.Context)
+}
+
+type MessageController struct {
+	service services.IMessageService
+}
+
+func NewMessageController(service services.IMessageService) IMessageController {
+	return &MessageController{service: service}
+}
+
+func (c *MessageController) Send(ctx *gin.Context) {
+	userId, orderId, ok := orderParams(ctx)
+	if !ok {
+		return
+	}
+
+	var input dto.SendMessageInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	message, err := c.service.Send(orderId, userId, input)
+	if err != nil {
+		writeOrderError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"data": message})
+}
+
+func (c *MessageController) FindByOrder(ctx *gin.Context) {
+	userId, orderId, ok := orderParams(ctx)
+	if !ok {
+		return
+	}
+
+	var query dto.PageQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := c.service.FindByOrder(orderId, userId, query)
+	if err != nil {
+		writeOrderError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": page.Messages, "total": page.Total, "unread": page.Unread})
+}
+
+func (c *MessageController) MarkRead(ctx *gin.Context) {
+	userId, orderId, ok := orderParams(ctx)
+	if !ok {
+		return
+	}
+
+	count, err := c.service.MarkRead(orderId, userId)
+	if err != nil {
+		writeOrderError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": gin.H{"read": count}})
+}
+
+func (c *MessageController) CountUnread(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	userId := user.(*models.User).ID
+
+	output, err := c.service.CountUnread(userId)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": output})
+}
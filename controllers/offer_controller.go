@@ -0,0 +1,162 @@
+package controllers
+
+import (
+	"gin-fleamarket/dto"
+	"gin-fleamarket/models"
+	"gin-fleamarket/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type IOfferController interface {
+	Create(ctx *gin.Context)
+	FindByItem(ctx *gin.Context)
+	Accept(ctx *gin.Context)
+	Reject(ctx *gin.Context)
+	Counter(ctx *gin.Context)
+}
+
+type OfferController struct {
+	service services.IOfferService
+}
+
+func NewOfferController(service services.IOfferService) IOfferController {
+	return &OfferController{service: service}
+}
+
+func (c *OfferController) Create(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	userId := user.(*models.User).ID
+
+	itemId, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		return
+	}
+
+	var input dto.CreateOfferInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	offer, err := c.service.Create(uint(itemId), userId, input)
+	if err != nil {
+		writeOfferError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"data": offer})
+}
+
+func (c *OfferController) FindByItem(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	userId := user.(*models.User).ID
+
+	itemId, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		return
+	}
+
+	offers, err := c.service.FindByItem(uint(itemId), userId)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": offers})
+}
+
+func (c *OfferController) Accept(ctx *gin.Context) {
+	userId, offerId, ok := offerParams(ctx)
+	if !ok {
+		return
+	}
+
+	offer, err := c.service.Accept(offerId, userId)
+	if err != nil {
+		writeOfferError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": offer})
+}
+
+func (c *OfferController) Reject(ctx *gin.Context) {
+	userId, offerId, ok := offerParams(ctx)
+	if !ok {
+		return
+	}
+
+	offer, err := c.service.Reject(offerId, userId)
+	if err != nil {
+		writeOfferError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": offer})
+}
+
+func (c *OfferController) Counter(ctx *gin.Context) {
+	userId, offerId, ok := offerParams(ctx)
+	if !ok {
+		return
+	}
+
+	var input dto.CreateOfferInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	offer, err := c.service.Counter(offerId, userId, input)
+	if err != nil {
+		writeOfferError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"data": offer})
+}
+
+func offerParams(ctx *gin.Context) (userId uint, offerId uint, ok bool) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return 0, 0, false
+	}
+
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		return 0, 0, false
+	}
+	return user.(*models.User).ID, uint(id), true
+}
+
+func writeOfferError(ctx *gin.Context, err error) {
+	switch err.Error() {
+	case "Item not found", "Offer not found":
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case "Invalid offer price":
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case "Cannot offer on own item", "Forbidden":
+		ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case "Item not available", "Offer already exists", "Invalid offer status", "Offer expired", "Version conflict":
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+	}
+}
@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"gin-fleamarket/models"
+	"gin-fleamarket/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type IOrderController interface {
+	FindById(ctx *gin.Context)
+	Purchase(ctx *gin.Context)
+}
+
+type OrderController struct {
+	service services.IOrderService
+}
+
+func NewOrderController(service services.IOrderService) IOrderController {
+	return &OrderController{service: service}
+}
+
+func (c *OrderController) FindById(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	userId := user.(*models.User).ID
+
+	orderId, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		return
+	}
+
+	order, err := c.service.FindById(uint(orderId), userId)
+	if err != nil {
+		writeOrderError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": order})
+}
+
+func (c *OrderController) Purchase(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	userId := user.(*models.User).ID
+
+	itemId, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		return
+	}
+
+	order, err := c.service.Purchase(uint(itemId), userId)
+	if err != nil {
+		writeOrderError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"data": order})
+}
+
+func writeOrderError(ctx *gin.Context, err error) {
+	switch err.Error() {
+	case "Item not found", "Order not found":
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case "Cannot purchase own item", "Forbidden":
+		ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case "Item not available", "Invalid order status", "Already rated":
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+	}
+}
@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"errors"
+	"gin-fleamarket/dto"
+	"gin-fleamarket/models"
+	"gin-fleamarket/services"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type IOrderWorkflowController interface {
+	Ship(ctx *gin.Context)
+	ConfirmReceipt(ctx *gin.Context)
+	Rate(ctx *gin.Context)
+	Cancel(ctx *gin.Context)
+	FindEvents(ctx *gin.Context)
+}
+
+type OrderWorkflowController struct {
+	service services.IOrderWorkflowService
+}
+
+func NewOrderWorkflowController(service services.IOrderWorkflowService) IOrderWorkflowController {
+	return &OrderWorkflowController{service: service}
+}
+
+func (c *OrderWorkflowController) Ship(ctx *gin.Context) {
+	userId, orderId, ok := orderParams(ctx)
+	if !ok {
+		return
+	}
+
+	var input dto.ShipOrderInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	order, err := c.service.Ship(orderId, userId, input)
+	if err != nil {
+		writeOrderError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": order})
+}
+
+func (c *OrderWorkflowController) ConfirmReceipt(ctx *gin.Context) {
+	userId, orderId, ok := orderParams(ctx)
+	if !ok {
+		return
+	}
+
+	order, err := c.service.ConfirmReceipt(orderId, userId)
+	if err != nil {
+		writeOrderError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": order})
+}
+
+func (c *OrderWorkflowController) Rate(ctx *gin.Context) {
+	userId, orderId, ok := orderParams(ctx)
+	if !ok {
+		return
+	}
+
+	var input dto.RateOrderInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	order, err := c.service.Rate(orderId, userId, input)
+	if err != nil {
+		writeOrderError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": order})
+}
+
+func (c *OrderWorkflowController) Cancel(ctx *gin.Context) {
+	userId, orderId, ok := orderParams(ctx)
+	if !ok {
+		return
+	}
+
+	// 取り消し理由は任意なので、本文がなくてもよい
+	var input dto.CancelOrderInput
+	if err := ctx.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	order, err := c.service.Cancel(orderId, userId, input)
+	if err != nil {
+		writeOrderError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": order})
+}
+
+func (c *OrderWorkflowController) FindEvents(ctx *gin.Context) {
+	userId, orderId, ok := orderParams(ctx)
+	if !ok {
+		return
+	}
+
+	events, err := c.service.FindEvents(orderId, userId)
+	if err != nil {
+		writeOrderError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": events})
+}
+
+// orderParams はログインユーザーのIDとパスの注文IDを取り出します。取り出せない場合はレスポンスを書き込んで ok に false を返します
+func orderParams(ctx *gin.Context) (userId uint, orderId uint, ok bool) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return 0, 0, false
+	}
+
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		return 0, 0, false
+	}
+	return user.(*models.User).ID, uint(id), true
+}
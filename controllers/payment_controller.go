@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"gin-fleamarket/models"
+	"gin-fleamarket/payment"
+	"gin-fleamarket/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type IPaymentController interface {
+	Pay(ctx *gin.Context)
+	Webhook(ctx *gin.Context)
+}
+
+type PaymentController struct {
+	service services.IPaymentService
+}
+
+func NewPaymentController(service services.IPaymentService) IPaymentController {
+	return &PaymentController{service: service}
+}
+
+func (c *PaymentController) Pay(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	userId := user.(*models.User).ID
+
+	orderId, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		return
+	}
+
+	order, err := c.service.Pay(uint(orderId), userId)
+	if err != nil {
+		writeOrderError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{"data": order})
+}
+
+func (c *PaymentController) Webhook(ctx *gin.Context) {
+	body, err := ctx.GetRawData()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event"})
+		return
+	}
+
+	err = c.service.HandleWebhook(body, ctx.GetHeader(payment.SignatureHeader))
+	if err != nil {
+		switch err.Error() {
+		case "Invalid signature":
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case "Invalid event":
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case "Order not found":
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "ok"})
+}
@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"gin-fleamarket/dto"
+	"gin-fleamarket/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type IReviewController interface {
+	FindByUser(ctx *gin.Context)
+}
+
+type ReviewController struct {
+	service services.IReviewService
+}
+
+func NewReviewController(service services.IReviewService) IReviewController {
+	return &ReviewController{service: service}
+}
+
+func (c *ReviewController) FindByUser(ctx *gin.Context) {
+	userId, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		return
+	}
+
+	var query dto.FindReviewsQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := c.service.FindByUser(uint(userId), query)
+	if err != nil {
+		if err.Error() == "User not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": page.Reviews, "total": page.Total, "reputation": page.Reputation})
+}
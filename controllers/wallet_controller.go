@@ -0,0 +1,161 @@
+package controllers
+
+import (
+	"gin-fleamarket/dto"
+	"gin-fleamarket/models"
+	"gin-fleamarket/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type IWalletController interface {
+	FindWallet(ctx *gin.Context)
+	FindTransactions(ctx *gin.Context)
+	RequestPayout(ctx *gin.Context)
+	FindPayouts(ctx *gin.Context)
+	FindAllPayouts(ctx *gin.Context)
+	MarkPayoutPaid(ctx *gin.Context)
+}
+
+type WalletController struct {
+	service services.IWalletService
+}
+
+func NewWalletController(service services.IWalletService) IWalletController {
+	return &WalletController{service: service}
+}
+
+func (c *WalletController) FindWallet(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	userId := user.(*models.User).ID
+
+	wallet, err := c.service.FindWallet(userId)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": wallet})
+}
+
+func (c *WalletController) FindTransactions(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	userId := user.(*models.User).ID
+
+	var query dto.PageQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := c.service.FindTransactions(userId, query)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": page.Entries, "total": page.Total})
+}
+
+func (c *WalletController) RequestPayout(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	userId := user.(*models.User).ID
+
+	var input dto.CreatePayoutInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	payout, err := c.service.RequestPayout(userId, input)
+	if err != nil {
+		writeWalletError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"data": payout})
+}
+
+func (c *WalletController) FindPayouts(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	userId := user.(*models.User).ID
+
+	var query dto.FindPayoutsQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := c.service.FindPayouts(userId, query)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": page.Payouts, "total": page.Total})
+}
+
+func (c *WalletController) FindAllPayouts(ctx *gin.Context) {
+	var query dto.FindPayoutsQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := c.service.FindAllPayouts(query)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": page.Payouts, "total": page.Total})
+}
+
+func (c *WalletController) MarkPayoutPaid(ctx *gin.Context) {
+	payoutId, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		return
+	}
+
+	payout, err := c.service.MarkPayoutPaid(uint(payoutId))
+	if err != nil {
+		writeWalletError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": payout})
+}
+
+func writeWalletError(ctx *gin.Context, err error) {
+	switch err.Error() {
+	case "Payout not found", "User not found":
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case "Insufficient balance", "Invalid payout status":
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+	}
+}
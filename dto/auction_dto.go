@@ -0,0 +1,17 @@
+package dto
+
+import "gin-fleamarket/models"
+
+type PlaceBidInput struct {
+	Amount uint `json:"amount" binding:"required,min=1,max=999999"`
+}
+
+type BidOutput struct {
+	models.Bid
+	Auction models.Auction `json:"auction"`
+}
+
+type BidPage struct {
+	Bids  []models.Bid
+	Total int64
+}
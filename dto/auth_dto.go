@@ -8,3 +8,57 @@ type LoginInput struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=8"`
 }
+
+type VerifyEmailQuery struct {
+	Token string `form:"token" binding:"required"`
+}
+
+type ResendVerificationInput struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ForgotPasswordInput struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordInput struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=8"`
+}
+
+type ChangePasswordInput struct {
+	CurrentPassword string `json:"currentPassword" binding:"required"`
+	NewPassword     string `json:"newPassword" binding:"required,min=8"`
+}
+
+type RefreshInput struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
+
+// RefreshToken を指定した場合は、リフレッシュトークンもあわせて失効させます
+type LogoutInput struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// TokenOutput の ExpiresIn はアクセストークンの有効期限までの秒数です
+type TokenOutput struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+	ExpiresIn    int64  `json:"expiresIn"`
+}
+
+// JWKOutput は RFC 7517 の JSON Web Key です。鍵の種類に応じて n/e または crv/x が設定されます
+type JWKOutput struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKSetOutput struct {
+	Keys []JWKOutput `json:"keys"`
+}
@@ -0,0 +1,12 @@
+package dto
+
+type CreateCategoryInput struct {
+	Name     string `json:"name" binding:"required,min=1,max=50"`
+	ParentID *uint  `json:"parentId"`
+}
+
+// ParentID に0を指定するとルートカテゴリに移動します
+type UpdateCategoryInput struct {
+	Name     *string `json:"name" binding:"omitnil,min=1,max=50"`
+	ParentID *uint   `json:"parentId"`
+}
@@ -0,0 +1,20 @@
+package dto
+
+import "gin-fleamarket/models"
+
+// ParentID を指定した場合は質問への回答になります。回答できるのは出品者のみです
+type CreateItemCommentInput struct {
+	Body     string `json:"body" binding:"required,max=1000"`
+	ParentID *uint  `json:"parentId"`
+}
+
+type ItemCommentPage struct {
+	Comments []models.ItemComment
+	Total    int64
+}
+
+type NotificationPage struct {
+	Notifications []models.Notification
+	Total         int64
+	Unread        int64
+}
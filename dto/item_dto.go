@@ -1,14 +1,68 @@
 package dto
 
+import (
+	"gin-fleamarket/models"
+	"time"
+)
+
 type CreateItemInput struct {
 	Name        string `json:"name" binding:"required,min=2"`
 	Price       uint   `json:"price" binding:"required,min=1,max=999999"`
 	Description string `json:"description"`
+	CategoryID  *uint  `json:"categoryId"`
+	Status      string `json:"status" binding:"omitempty,oneof=draft listed"`
+	ListingType string `json:"listingType" binding:"omitempty,oneof=fixed auction"`
+	// オークション形式の場合は Price が開始価格になります
+	Auction *CreateAuctionInput `json:"auction" binding:"required_if=ListingType auction"`
+}
+
+type CreateAuctionInput struct {
+	ReservePrice uint      `json:"reservePrice" binding:"max=999999"`
+	EndsAt       time.Time `json:"endsAt" binding:"required"`
 }
 
 type UpdateItemInput struct {
 	Name        *string `json:"name" binding:"omitnil,min=2"`
 	Price       *uint   `json:"price" binding:"omitnil,min=1,max=999999"`
 	Description *string `json:"description"`
-	SoldOut     *bool   `json:"soldOut"`
+	Status      *string `json:"status" binding:"omitnil,oneof=draft listed reserved sold shipped completed cancelled"`
+	CategoryID  *uint   `json:"categoryId"`
+}
+
+// cursor を指定した場合は offset より cursor が優先されます
+type FindItemsQuery struct {
+	Cursor   string   `form:"cursor"`
+	Limit    int      `form:"limit" binding:"omitempty,min=1,max=100"`
+	Offset   int      `form:"offset" binding:"omitempty,min=0"`
+	MinPrice *uint    `form:"minPrice" binding:"omitnil,max=999999"`
+	MaxPrice *uint    `form:"maxPrice" binding:"omitnil,max=999999"`
+	SoldOut  *bool    `form:"soldOut"`
+	Status   []string `form:"status" binding:"omitempty,dive,oneof=draft listed reserved sold shipped completed cancelled"`
+	UserID   *uint    `form:"userId"`
+	Category *uint    `form:"category"`
+	Sort     string   `form:"sort" binding:"omitempty,oneof=newest price_asc price_desc"`
+}
+
+type SearchItemsQuery struct {
+	Q      string `form:"q" binding:"required"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=100"`
+	Offset int    `form:"offset" binding:"omitempty,min=0"`
+}
+
+type ItemPage struct {
+	Items      []models.Item
+	NextCursor *string
+	Total      int64
+}
+
+type SellerSummary struct {
+	ID           uint              `json:"id"`
+	MemberSince  time.Time         `json:"memberSince"`
+	ListingCount int64             `json:"listingCount"`
+	Reputation   ReputationSummary `json:"reputation"`
+}
+
+type ItemDetailOutput struct {
+	models.Item
+	Seller SellerSummary `json:"seller"`
 }
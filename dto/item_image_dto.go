@@ -0,0 +1,5 @@
+package dto
+
+type ReorderItemImagesInput struct {
+	ImageIDs []uint `json:"imageIds" binding:"required"`
+}
@@ -0,0 +1,25 @@
+package dto
+
+import (
+	"gin-fleamarket/models"
+	"time"
+)
+
+type LikeOutput struct {
+	ItemID    uint `json:"itemId"`
+	Liked     bool `json:"liked"`
+	LikeCount uint `json:"likeCount"`
+}
+
+// Deleted は削除されたか、非公開になったアイテムの場合に true になります
+type LikedItemOutput struct {
+	Item    models.Item `json:"item"`
+	LikedAt time.Time   `json:"likedAt"`
+	SoldOut bool        `json:"soldOut"`
+	Deleted bool        `json:"deleted"`
+}
+
+type LikedItemPage struct {
+	Items []LikedItemOutput
+	Total int64
+}
@@ -0,0 +1,29 @@
+package dto
+
+import "time"
+
+type ExportItemsQuery struct {
+	Format string `form:"format" binding:"omitempty,oneof=csv json"`
+}
+
+type ImportRowResult struct {
+	Row    int      `json:"row"`
+	ItemID *uint    `json:"itemId,omitempty"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+type ImportItemsOutput struct {
+	Results []ImportRowResult `json:"results"`
+	Created int               `json:"created"`
+	Failed  int               `json:"failed"`
+}
+
+type ExportItemRow struct {
+	ID          uint      `json:"id"`
+	Name        string    `json:"name"`
+	Price       uint      `json:"price"`
+	Description string    `json:"description"`
+	CategoryID  *uint     `json:"categoryId"`
+	Status      string    `json:"status"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
@@ -0,0 +1,24 @@
+package dto
+
+import "gin-fleamarket/models"
+
+type SendMessageInput struct {
+	Body string `json:"body" binding:"required,max=1000"`
+}
+
+type MessagePage struct {
+	Messages []models.OrderMessage
+	Total    int64
+	// ログインユーザーが受信した未読メッセージの件数です
+	Unread int64
+}
+
+type UnreadCount struct {
+	OrderID uint  `json:"orderId"`
+	Count   int64 `json:"count"`
+}
+
+type UnreadMessagesOutput struct {
+	Total  int64         `json:"total"`
+	Orders []UnreadCount `json:"orders"`
+}
@@ -0,0 +1,5 @@
+package dto
+
+type CreateOfferInput struct {
+	Price uint `json:"price" binding:"required,min=1,max=999999"`
+}
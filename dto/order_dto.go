@@ -0,0 +1,14 @@
+package dto
+
+type ShipOrderInput struct {
+	TrackingNumber string `json:"trackingNumber" binding:"required,max=64"`
+}
+
+type RateOrderInput struct {
+	Rating  string `json:"rating" binding:"required,oneof=good normal bad"`
+	Comment string `json:"comment" binding:"max=500"`
+}
+
+type CancelOrderInput struct {
+	Reason string `json:"reason" binding:"max=500"`
+}
@@ -0,0 +1,7 @@
+package dto
+
+// PageQuery はオフセット方式でページングする一覧APIのクエリです
+type PageQuery struct {
+	Limit  int `form:"limit" binding:"omitempty,min=1,max=100"`
+	Offset int `form:"offset" binding:"omitempty,min=0"`
+}
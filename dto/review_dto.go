@@ -0,0 +1,33 @@
+package dto
+
+import "time"
+
+type ReputationSummary struct {
+	Good   int64 `json:"good"`
+	Normal int64 `json:"normal"`
+	Bad    int64 `json:"bad"`
+	Total  int64 `json:"total"`
+}
+
+// role を指定した場合は、その立場で受け取った評価だけを返します
+type FindReviewsQuery struct {
+	PageQuery
+	Rating string `form:"rating" binding:"omitempty,oneof=good normal bad"`
+	Role   string `form:"role" binding:"omitempty,oneof=buyer seller"`
+}
+
+type ReviewOutput struct {
+	ID        uint      `json:"id"`
+	OrderID   uint      `json:"orderId"`
+	RaterID   uint      `json:"raterId"`
+	RaterRole string    `json:"raterRole"`
+	Rating    string    `json:"rating"`
+	Comment   string    `json:"comment"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type ReviewPage struct {
+	Reviews    []ReviewOutput
+	Total      int64
+	Reputation ReputationSummary
+}
@@ -0,0 +1,27 @@
+package dto
+
+import "gin-fleamarket/models"
+
+type WalletOutput struct {
+	Available int64 `json:"available"`
+	Locked    int64 `json:"locked"`
+}
+
+type CreatePayoutInput struct {
+	Amount uint `json:"amount" binding:"required,min=1"`
+}
+
+type FindPayoutsQuery struct {
+	PageQuery
+	Status string `form:"status" binding:"omitempty,oneof=requested paid"`
+}
+
+type LedgerEntryPage struct {
+	Entries []models.LedgerEntry
+	Total   int64
+}
+
+type PayoutPage struct {
+	Payouts []models.Payout
+	Total   int64
+}
@@ -0,0 +1,38 @@
+package infra
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// GetEnvDuration は環境変数を time.Duration として読み込みます
+// 未設定または不正な値の場合は fallback を返します
+func GetEnvDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid duration for %s: %s", key, value)
+		return fallback
+	}
+	return d
+}
+
+// GetEnvUint は環境変数を uint として読み込みます
+// 未設定または不正な値の場合は fallback を返します
+func GetEnvUint(key string, fallback uint) uint {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		log.Printf("Invalid number for %s: %s", key, value)
+		return fallback
+	}
+	return uint(n)
+}
@@ -37,5 +37,14 @@ func SetupDB() *gorm.DB {
 		panic("Failed to connect to database")
 	}
 
+	if env != "prod" {
+		// インメモリのSQLiteは接続ごとに別のDBになるため、接続を1つに制限する
+		sqlDB, err := db.DB()
+		if err != nil {
+			panic("Failed to connect to database")
+		}
+		sqlDB.SetMaxOpenConns(1)
+	}
+
 	return db
 }
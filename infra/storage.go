@@ -0,0 +1,78 @@
+package infra
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// IStorage はアップロードされたファイルの保存先を抽象化します
+type IStorage interface {
+	Save(key string, content io.Reader) error
+	Delete(key string) error
+	URL(key string) string
+}
+
+type LocalStorage struct {
+	baseDir string
+	baseURL string
+}
+
+func NewLocalStorage(baseDir string, baseURL string) IStorage {
+	return &LocalStorage{baseDir: baseDir, baseURL: baseURL}
+}
+
+// SetupStorage は STORAGE_DIR 配下にファイルを保存するストレージを返します
+func SetupStorage() (IStorage, string) {
+	dir := os.Getenv("STORAGE_DIR")
+	if dir == "" {
+		dir = "uploads"
+	}
+	return NewLocalStorage(dir, "/uploads"), dir
+}
+
+func (s *LocalStorage) Save(key string, content io.Reader) error {
+	fullPath, err := s.fullPath(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.Create(fullPath)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, content); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (s *LocalStorage) Delete(key string) error {
+	fullPath, err := s.fullPath(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(fullPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *LocalStorage) URL(key string) string {
+	return path.Join(s.baseURL, key)
+}
+
+// fullPath はキーを保存先ディレクトリ配下のパスに変換します
+func (s *LocalStorage) fullPath(key string) (string, error) {
+	localPath := filepath.FromSlash(key)
+	if !filepath.IsLocal(localPath) {
+		return "", errors.New("Invalid storage key")
+	}
+	return filepath.Join(s.baseDir, localPath), nil
+}
@@ -0,0 +1,23 @@
+package mail
+
+import "log"
+
+// AsyncMailer は送信を待たずに返します
+// 送信にかかる時間の違いからメールアドレスが登録済みかどうかを知られないように、SMTP での送信に使います
+type AsyncMailer struct {
+	mailer IMailer
+}
+
+func NewAsyncMailer(mailer IMailer) IMailer {
+	return &AsyncMailer{mailer: mailer}
+}
+
+// Send implements IMailer.
+func (m *AsyncMailer) Send(message Message) error {
+	go func() {
+		if err := m.mailer.Send(message); err != nil {
+			log.Printf("Failed to send mail: %v", err)
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,33 @@
+package mail
+
+import (
+	"os"
+
+	"gorm.io/gorm"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// IMailer はメールの送信を抽象化します
+type IMailer interface {
+	Send(message Message) error
+}
+
+// SetupMailer は環境変数からメールの送信方法を選びます
+// MAIL_DRIVER が smtp の場合は SMTP サーバーから非同期に送信し、それ以外の場合は送信せずにデータベースの outbox_mails に保存します
+func SetupMailer(db *gorm.DB) IMailer {
+	if os.Getenv("MAIL_DRIVER") == "smtp" {
+		return NewAsyncMailer(NewSMTPMailer(
+			os.Getenv("SMTP_HOST"),
+			os.Getenv("SMTP_PORT"),
+			os.Getenv("SMTP_USERNAME"),
+			os.Getenv("SMTP_PASSWORD"),
+			os.Getenv("MAIL_FROM"),
+		))
+	}
+	return NewOutboxMailer(db)
+}
@@ -0,0 +1,20 @@
+package mail
+
+import (
+	"gin-fleamarket/models"
+
+	"gorm.io/gorm"
+)
+
+type OutboxMailer struct {
+	db *gorm.DB
+}
+
+func NewOutboxMailer(db *gorm.DB) IMailer {
+	return &OutboxMailer{db: db}
+}
+
+// Send implements IMailer.
+func (m *OutboxMailer) Send(message Message) error {
+	return m.db.Create(&models.OutboxMail{To: message.To, Subject: message.Subject, Body: message.Body}).Error
+}
@@ -0,0 +1,26 @@
+package mail
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestBuildMessage(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	message := buildMessage("noreply@example.com", Message{
+		To:      "test@example.com",
+		Subject: "メールアドレスの確認",
+		Body:    "1行目\n2行目",
+	}, now)
+
+	headers, body, found := strings.Cut(string(message), "\r\n\r\n")
+	assert.Assert(t, found)
+	assert.Assert(t, strings.Contains(headers, "From: noreply@example.com\r\n"))
+	assert.Assert(t, strings.Contains(headers, "To: test@example.com\r\n"))
+	assert.Assert(t, strings.Contains(headers, "Subject: =?UTF-8?b?"))
+	assert.Assert(t, strings.Contains(headers, "Date: Fri, 02 Jan 2026 03:04:05 +0000"))
+	assert.Equal(t, "1行目\r\n2行目", body)
+}
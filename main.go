@@ -3,29 +3,109 @@ package main
 import (
 	"gin-fleamarket/controllers"
 	"gin-fleamarket/infra"
+	"gin-fleamarket/mail"
 	"gin-fleamarket/middlewares"
+	"gin-fleamarket/payment"
 	"gin-fleamarket/repositories"
 	"gin-fleamarket/services"
 	"log"
 	"os"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-func setupRouter(db *gorm.DB) *gin.Engine {
+// backgroundRunners はHTTPハンドラと同じサービスのインスタンスで定期的に実行する処理です
+type backgroundRunners struct {
+	itemPurgeService         services.IItemPurgeService
+	auctionService           services.IAuctionService
+	offerService             services.IOfferService
+	revokedTokenPurgeService services.IRevokedTokenPurgeService
+}
+
+func (b *backgroundRunners) start() {
+	go b.itemPurgeService.Run(time.Hour)
+	go b.auctionService.Run(time.Minute)
+	go b.offerService.Run(time.Minute)
+	go b.revokedTokenPurgeService.Run(time.Hour)
+}
+
+func setupRouter(db *gorm.DB) (*gin.Engine, *backgroundRunners) {
 	// itemRepository := repositories.NewItemMemoryRepository(items)
 	itemRepository := repositories.NewItemRepository(db)
-	itemService := services.NewItemService(itemRepository)
+	searchRepository := repositories.NewSearchRepository(db)
+	searchService := services.NewSearchService(searchRepository)
+	categoryRepository := repositories.NewCategoryRepository(db)
+	categoryService := services.NewCategoryService(categoryRepository, itemRepository)
+	categoryController := controllers.NewCategoryController(categoryService)
+
+	userRepository := repositories.NewUserRepository(db)
+	itemService := services.NewItemService(itemRepository, userRepository, searchService, categoryService)
 	itemController := controllers.NewItemController(itemService)
 
+	itemTransferService := services.NewItemTransferService(itemService)
+	itemTransferController := controllers.NewItemTransferController(itemTransferService)
+
+	orderRepository := repositories.NewOrderRepository(db)
+	orderService := services.NewOrderService(orderRepository)
+	orderController := controllers.NewOrderController(orderService)
+
+	paymentProvider, paymentWebhookSecret := payment.SetupProvider()
+	paymentService := services.NewPaymentService(orderRepository, paymentProvider, paymentWebhookSecret)
+	paymentController := controllers.NewPaymentController(paymentService)
+	orderWorkflowService := services.NewOrderWorkflowService(orderRepository, paymentProvider, infra.GetEnvUint("PLATFORM_FEE_PERCENT", 10))
+	orderWorkflowController := controllers.NewOrderWorkflowController(orderWorkflowService)
+
+	offerService := services.NewOfferService(orderRepository, infra.GetEnvDuration("OFFER_TTL", 48*time.Hour), infra.GetEnvDuration("OFFER_PURCHASE_WINDOW", 24*time.Hour))
+	offerController := controllers.NewOfferController(offerService)
+
+	auctionService := services.NewAuctionService(orderRepository, infra.GetEnvDuration("AUCTION_EXTENSION_WINDOW", 5*time.Minute))
+	auctionController := controllers.NewAuctionController(auctionService)
+
+	itemLikeRepository := repositories.NewItemLikeRepository(db)
+	itemLikeService := services.NewItemLikeService(itemLikeRepository, itemRepository)
+	itemLikeController := controllers.NewItemLikeController(itemLikeService)
+
+	notificationRepository := repositories.NewNotificationRepository(db)
+	notificationService := services.NewNotificationService(notificationRepository)
+	notificationController := controllers.NewNotificationController(notificationService)
+
+	itemCommentRepository := repositories.NewItemCommentRepository(db)
+	itemCommentService := services.NewItemCommentService(itemCommentRepository, itemRepository, notificationRepository)
+	itemCommentController := controllers.NewItemCommentController(itemCommentService)
+
+	messageRepository := repositories.NewMessageRepository(db)
+	messageService := services.NewMessageService(messageRepository, orderRepository)
+	messageController := controllers.NewMessageController(messageService)
+
+	reviewRepository := repositories.NewReviewRepository(db)
+	reviewService := services.NewReviewService(reviewRepository, userRepository)
+	reviewController := controllers.NewReviewController(reviewService)
+
+	walletRepository := repositories.NewWalletRepository(db)
+	walletService := services.NewWalletService(walletRepository)
+	walletController := controllers.NewWalletController(walletService)
+
+	storage, storageDir := infra.SetupStorage()
+	itemImageRepository := repositories.NewItemImageRepository(db)
+	itemImageService := services.NewItemImageService(itemImageRepository, itemRepository, storage)
+	itemImageController := controllers.NewItemImageController(itemImageService)
+	itemPurgeService := services.NewItemPurgeService(itemRepository, itemImageRepository, storage, infra.GetEnvDuration("ITEM_TRASH_RETENTION", 30*24*time.Hour))
+
+	tokenKeys, err := services.SetupTokenKeys()
+	if err != nil {
+		log.Fatalln("Failed to load JWT keys:", err)
+	}
 	authRepository := repositories.NewAuthRepository(db)
-	authService := services.NewAuthService(authRepository)
+	authService := services.NewAuthService(authRepository, mail.SetupMailer(db), tokenKeys, infra.GetEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour))
 	authController := controllers.NewAuthController(authService)
+	revokedTokenPurgeService := services.NewRevokedTokenPurgeService(authRepository)
 
 	r := gin.Default()
 	r.Use(cors.Default())
+	r.Static("/uploads", storageDir)
 	r.GET("/ping", func(c *gin.Context) {
 		c.JSON(200, gin.H{
 			"message": "pong",
@@ -34,17 +114,98 @@ func setupRouter(db *gorm.DB) *gin.Engine {
 	itemRouter := r.Group("/items")
 	itemRouterWithAuth := r.Group("/items", middlewares.AuthMiddleware(authService))
 	authRouter := r.Group("/auth")
+	authRouterWithAuth := r.Group("/auth", middlewares.AuthMiddleware(authService))
+	meRouter := r.Group("/me", middlewares.AuthMiddleware(authService))
+	userRouter := r.Group("/users")
+	orderRouterWithAuth := r.Group("/orders", middlewares.AuthMiddleware(authService))
+	offerRouterWithAuth := r.Group("/offers", middlewares.AuthMiddleware(authService))
+	categoryRouter := r.Group("/categories")
+	categoryRouterWithAdmin := r.Group("/categories", middlewares.AuthMiddleware(authService), middlewares.AdminMiddleware())
+	adminRouter := r.Group("/admin", middlewares.AuthMiddleware(authService), middlewares.AdminMiddleware())
 
 	itemRouter.GET("", itemController.FindAll)
-	itemRouterWithAuth.GET("/:id", itemController.FindById)
-	itemRouterWithAuth.POST("", itemController.Create)
+	itemRouter.GET("/search", itemController.Search)
+	itemRouter.GET("/:id", itemController.FindPublicById)
+	itemRouter.GET("/:id/bids", auctionController.FindBids)
+	itemRouter.GET("/:id/comments", itemCommentController.FindByItem)
+	itemRouterWithAuth.POST("", middlewares.VerifiedMiddleware(), itemController.Create)
 	itemRouterWithAuth.PUT("/:id", itemController.Update)
 	itemRouterWithAuth.DELETE("/:id", itemController.Delete)
+	itemRouterWithAuth.POST("/:id/restore", itemController.Restore)
+	itemRouterWithAuth.POST("/:id/purchase", orderController.Purchase)
+	itemRouterWithAuth.POST("/:id/bids", auctionController.PlaceBid)
+	itemRouterWithAuth.GET("/:id/offers", offerController.FindByItem)
+	itemRouterWithAuth.POST("/:id/offers", offerController.Create)
+	itemRouterWithAuth.POST("/:id/like", itemLikeController.Like)
+	itemRouterWithAuth.DELETE("/:id/like", itemLikeController.Unlike)
+	itemRouterWithAuth.POST("/:id/comments", itemCommentController.Create)
+	itemRouterWithAuth.DELETE("/:id/comments/:commentId", itemCommentController.Delete)
+	itemRouterWithAuth.POST("/:id/images", itemImageController.Upload)
+	itemRouterWithAuth.PUT("/:id/images/order", itemImageController.Reorder)
+	itemRouterWithAuth.DELETE("/:id/images/:imageId", itemImageController.Delete)
+
+	meRouter.GET("/items", itemController.FindMine)
+	meRouter.GET("/items/trash", itemController.FindTrash)
+	meRouter.POST("/items/import", middlewares.VerifiedMiddleware(), itemTransferController.Import)
+	meRouter.GET("/items/export", itemTransferController.Export)
+	meRouter.GET("/items/:id", itemController.FindById)
+	meRouter.GET("/likes", itemLikeController.FindMine)
+	meRouter.PUT("/password", authController.ChangePassword)
+	meRouter.GET("/wallet", walletController.FindWallet)
+	meRouter.GET("/wallet/transactions", walletController.FindTransactions)
+	meRouter.GET("/wallet/payouts", walletController.FindPayouts)
+	meRouter.POST("/wallet/payouts", walletController.RequestPayout)
+	meRouter.GET("/messages/unread", messageController.CountUnread)
+	meRouter.GET("/notifications", notificationController.FindMine)
+	meRouter.POST("/notifications/read", notificationController.MarkRead)
+
+	userRouter.GET("/:id/reviews", reviewController.FindByUser)
+
+	orderRouterWithAuth.GET("/:id", orderController.FindById)
+	orderRouterWithAuth.GET("/:id/events", orderWorkflowController.FindEvents)
+	orderRouterWithAuth.GET("/:id/messages", messageController.FindByOrder)
+	orderRouterWithAuth.POST("/:id/messages", messageController.Send)
+	orderRouterWithAuth.POST("/:id/messages/read", messageController.MarkRead)
+	orderRouterWithAuth.POST("/:id/pay", paymentController.Pay)
+	orderRouterWithAuth.POST("/:id/ship", orderWorkflowController.Ship)
+	orderRouterWithAuth.POST("/:id/receive", orderWorkflowController.ConfirmReceipt)
+	orderRouterWithAuth.POST("/:id/rate", orderWorkflowController.Rate)
+	orderRouterWithAuth.POST("/:id/cancel", orderWorkflowController.Cancel)
+
+	offerRouterWithAuth.POST("/:id/accept", offerController.Accept)
+	offerRouterWithAuth.POST("/:id/reject", offerController.Reject)
+	offerRouterWithAuth.POST("/:id/counter", offerController.Counter)
+
+	r.POST("/webhooks/payments", paymentController.Webhook)
+
+	categoryRouter.GET("", categoryController.FindAll)
+	categoryRouter.GET("/:id", categoryController.FindById)
+	categoryRouterWithAdmin.POST("", categoryController.Create)
+	categoryRouterWithAdmin.PUT("/:id", categoryController.Update)
+	categoryRouterWithAdmin.DELETE("/:id", categoryController.Delete)
+
+	adminRouter.GET("/payouts", walletController.FindAllPayouts)
+	adminRouter.POST("/payouts/:id/paid", walletController.MarkPayoutPaid)
 
 	authRouter.POST("/signup", authController.Signup)
+	authRouter.GET("/verify", authController.VerifyEmail)
+	authRouter.POST("/verify/resend", authController.ResendVerification)
+	authRouter.POST("/password/forgot", authController.ForgotPassword)
+	authRouter.POST("/password/reset", authController.ResetPassword)
 	authRouter.POST("/login", authController.Login)
+	authRouter.POST("/refresh", authController.Refresh)
+	authRouterWithAuth.POST("/logout", authController.Logout)
+	authRouterWithAuth.POST("/logout-all", authController.LogoutAll)
+
+	r.GET("/.well-known/jwks.json", authController.JWKS)
 
-	return r
+	runners := &backgroundRunners{
+		itemPurgeService:         itemPurgeService,
+		auctionService:           auctionService,
+		offerService:             offerService,
+		revokedTokenPurgeService: revokedTokenPurgeService,
+	}
+	return r, runners
 }
 
 func main() {
@@ -57,7 +218,8 @@ func main() {
 	// 	{ID: 3, Name: "商品3", Price: 3000, Description: "説明3", SoldOut: false},
 	// }
 
-	r := setupRouter(db)
+	r, runners := setupRouter(db)
+	runners.start()
 
 	r.Run("localhost:8080") // 0.0.0.0:8080 でサーバーを立てます。
 }
@@ -2,19 +2,32 @@ package main
 
 import (
 	"bytes"
+	"encoding/csv"
 	"encoding/json"
+	"fmt"
 	"gin-fleamarket/dto"
 	"gin-fleamarket/infra"
 	"gin-fleamarket/models"
+	"gin-fleamarket/payment"
 	"gin-fleamarket/services"
+	"image"
+	"image/png"
 	"log"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 	"gotest.tools/v3/assert"
 
@@ -38,17 +51,21 @@ func TestMain(m *testing.M) {
 
 func setupTestData(db *gorm.DB) {
 	items := []models.Item{
-		{Name: "テストアイテム1", Price: 1000, Description: "", SoldOut: false, UserID: 1},
-		{Name: "テストアイテム2", Price: 2000, Description: "テスト2", SoldOut: true, UserID: 1},
-		{Name: "テストアイテム3", Price: 3000, Description: "テスト3", SoldOut: false, UserID: 1},
+		{Name: "テストアイテム1", Price: 1000, Description: "", Status: models.ItemStatusListed, UserID: 1},
+		{Name: "テストアイテム2", Price: 2000, Description: "テスト2", Status: models.ItemStatusSold, UserID: 1},
+		{Name: "テストアイテム3", Price: 3000, Description: "テスト3", Status: models.ItemStatusListed, UserID: 1},
 	}
 
+	verifiedAt := time.Now()
 	users := []models.User{
-		{Email: "test1@example.com", Password: "test1pass"},
-		{Email: "test2@example.com", Password: "test2pass"},
+		{Email: "test1@example.com", Password: "test1pass", EmailVerifiedAt: &verifiedAt},
+		{Email: "test2@example.com", Password: "test2pass", IsAdmin: true, EmailVerifiedAt: &verifiedAt},
+		{Email: "test3@example.com", Password: "test3pass", EmailVerifiedAt: &verifiedAt},
 	}
 
 	for _, user := range users {
+		hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.MinCost)
+		user.Password = string(hashedPassword)
 		db.Create(&user)
 	}
 	for _, item := range items {
@@ -58,10 +75,10 @@ func setupTestData(db *gorm.DB) {
 
 func setup() *gin.Engine {
 	db := infra.SetupDB()
-	db.AutoMigrate(&models.Item{}, &models.User{})
+	db.AutoMigrate(&models.Item{}, &models.User{}, &models.ItemSearchToken{}, &models.Category{}, &models.ItemImage{}, &models.Order{}, &models.PaymentEvent{}, &models.OrderEvent{}, &models.OrderRating{}, &models.LedgerEntry{}, &models.Payout{}, &models.Offer{}, &models.Auction{}, &models.Bid{}, &models.OrderMessage{}, &models.ItemComment{}, &models.Notification{}, &models.ItemLike{}, &models.RefreshToken{}, &models.RevokedToken{}, &models.UserToken{}, &models.OutboxMail{})
 
 	setupTestData(db)
-	router := setupRouter(db)
+	router, _ := setupRouter(db)
 
 	return router
 }
@@ -85,12 +102,501 @@ func TestFindAll(t *testing.T) {
 	assert.Equal(t, 3, len(res["data"]))
 }
 
+type itemListResponse struct {
+	Data       []models.Item `json:"data"`
+	NextCursor *string       `json:"next_cursor"`
+	Total      int64         `json:"total"`
+}
+
+func TestFindAllWithFilters(t *testing.T) {
+	// テストのセットアップ
+	router := setup()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/items?soldOut=false&minPrice=1500", nil)
+
+	// APIリクエストの実行
+	router.ServeHTTP(w, req)
+
+	// APIの実行結果を取得
+	var res itemListResponse
+	json.Unmarshal([]byte(w.Body.String()), &res)
+
+	// アサーション
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int64(1), res.Total)
+	assert.Equal(t, 1, len(res.Data))
+	assert.Equal(t, "テストアイテム3", res.Data[0].Name)
+}
+
+func TestFindAllCursorPagination(t *testing.T) {
+	// テストのセットアップ
+	router := setup()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/items?sort=price_desc&limit=2", nil)
+	router.ServeHTTP(w, req)
+
+	var res itemListResponse
+	json.Unmarshal([]byte(w.Body.String()), &res)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int64(3), res.Total)
+	assert.Equal(t, 2, len(res.Data))
+	assert.Equal(t, uint(3000), res.Data[0].Price)
+	assert.Equal(t, uint(2000), res.Data[1].Price)
+	assert.Assert(t, res.NextCursor != nil)
+	priceDescCursor := *res.NextCursor
+
+	// 次のページを取得
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/items?sort=price_desc&limit=2&cursor="+priceDescCursor, nil)
+	router.ServeHTTP(w, req)
+
+	res = itemListResponse{}
+	json.Unmarshal([]byte(w.Body.String()), &res)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, len(res.Data))
+	assert.Equal(t, uint(1000), res.Data[0].Price)
+	assert.Assert(t, res.NextCursor == nil)
+
+	// ソート順と一致しないカーソルはエラー
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/items?sort=price_asc&cursor="+priceDescCursor, nil)
+	router.ServeHTTP(w, req)
+
+	var errRes map[string]string
+	json.Unmarshal([]byte(w.Body.String()), &errRes)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "Invalid cursor", errRes["error"])
+
+	// デコードできないカーソルはエラー
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/items?sort=price_asc&cursor=invalid", nil)
+	router.ServeHTTP(w, req)
+
+	errRes = map[string]string{}
+	json.Unmarshal([]byte(w.Body.String()), &errRes)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "Invalid cursor", errRes["error"])
+}
+
+func TestSearch(t *testing.T) {
+	// テストのセットアップ
+	router := setup()
+
+	token := loginAs(t, router, 1)
+
+	inputs := []dto.CreateItemInput{
+		{Name: "ヴィンテージ腕時計", Price: 5000, Description: "動作確認済みの腕時計です"},
+		{Name: "革のベルト", Price: 1500, Description: "腕時計の交換用ベルト"},
+		{Name: "ＩＰｈｏｎｅケース", Price: 800, Description: "未使用"},
+	}
+	for _, input := range inputs {
+		reqBody, _ := json.Marshal(input)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/items", bytes.NewBuffer(reqBody))
+		req.Header.Set("Authorization", "Bearer "+*token)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+	}
+
+	// 名前に含まれるアイテムが説明だけに含まれるアイテムより上位になる
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/items/search?q="+url.QueryEscape("腕時計"), nil)
+	router.ServeHTTP(w, req)
+
+	var res itemListResponse
+	json.Unmarshal([]byte(w.Body.String()), &res)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int64(2), res.Total)
+	assert.Equal(t, "ヴィンテージ腕時計", res.Data[0].Name)
+	assert.Equal(t, "革のベルト", res.Data[1].Name)
+
+	// 全角英字も半角小文字に正規化して検索できる
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/items/search?q=iphone", nil)
+	router.ServeHTTP(w, req)
+
+	res = itemListResponse{}
+	json.Unmarshal([]byte(w.Body.String()), &res)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, len(res.Data))
+
+	// 削除したアイテムは検索結果に含まれない
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", fmt.Sprintf("/items/%d", res.Data[0].ID), nil)
+	req.Header.Set("If-Match", `"1"`)
+	req.Header.Set("Authorization", "Bearer "+*token)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/items/search?q=iphone", nil)
+	router.ServeHTTP(w, req)
+
+	res = itemListResponse{}
+	json.Unmarshal([]byte(w.Body.String()), &res)
+
+	assert.Equal(t, int64(0), res.Total)
+}
+
+func TestCategories(t *testing.T) {
+	// テストのセットアップ
+	router := setup()
+
+	userToken := loginAs(t, router, 1)
+	adminToken := loginAs(t, router, 2)
+
+	createCategory := func(token string, input dto.CreateCategoryInput) (int, models.Category) {
+		reqBody, _ := json.Marshal(input)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/categories", bytes.NewBuffer(reqBody))
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(w, req)
+
+		var res map[string]models.Category
+		json.Unmarshal([]byte(w.Body.String()), &res)
+		return w.Code, res["data"]
+	}
+
+	// 管理者以外はカテゴリを作成できない
+	code, _ := createCategory(*userToken, dto.CreateCategoryInput{Name: "ファッション"})
+	assert.Equal(t, http.StatusForbidden, code)
+
+	code, root := createCategory(*adminToken, dto.CreateCategoryInput{Name: "ファッション"})
+	assert.Equal(t, http.StatusCreated, code)
+	_, mens := createCategory(*adminToken, dto.CreateCategoryInput{Name: "メンズ", ParentID: &root.ID})
+	_, ladies := createCategory(*adminToken, dto.CreateCategoryInput{Name: "レディース", ParentID: &root.ID})
+
+	createItem := func(categoryId uint) int {
+		reqBody, _ := json.Marshal(dto.CreateItemInput{Name: "カテゴリテスト", Price: 1000, CategoryID: &categoryId})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/items", bytes.NewBuffer(reqBody))
+		req.Header.Set("Authorization", "Bearer "+*userToken)
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// 末端ではないカテゴリや存在しないカテゴリは指定できない
+	assert.Equal(t, http.StatusBadRequest, createItem(root.ID))
+	assert.Equal(t, http.StatusBadRequest, createItem(999))
+	assert.Equal(t, http.StatusCreated, createItem(mens.ID))
+	assert.Equal(t, http.StatusCreated, createItem(ladies.ID))
+
+	// 親カテゴリで絞り込むと子孫カテゴリのアイテムも含まれる
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/items?category=%d", root.ID), nil)
+	router.ServeHTTP(w, req)
+
+	var res itemListResponse
+	json.Unmarshal([]byte(w.Body.String()), &res)
+	assert.Equal(t, int64(2), res.Total)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", fmt.Sprintf("/items?category=%d", mens.ID), nil)
+	router.ServeHTTP(w, req)
+
+	res = itemListResponse{}
+	json.Unmarshal([]byte(w.Body.String()), &res)
+	assert.Equal(t, int64(1), res.Total)
+
+	// ツリーの取得
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/categories", nil)
+	router.ServeHTTP(w, req)
+
+	var tree map[string][]models.Category
+	json.Unmarshal([]byte(w.Body.String()), &tree)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, len(tree["data"]))
+	assert.Equal(t, 2, len(tree["data"][0].Children))
+
+	// 子カテゴリを持つカテゴリは削除できない
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", fmt.Sprintf("/categories/%d", root.ID), nil)
+	req.Header.Set("Authorization", "Bearer "+*adminToken)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// ゴミ箱のアイテムが使っているカテゴリも、復元できるように削除できない
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", fmt.Sprintf("/items/%d", res.Data[0].ID), nil)
+	req.Header.Set("If-Match", `"1"`)
+	req.Header.Set("Authorization", "Bearer "+*userToken)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", fmt.Sprintf("/categories/%d", mens.ID), nil)
+	req.Header.Set("Authorization", "Bearer "+*adminToken)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", fmt.Sprintf("/items/%d/restore", res.Data[0].ID), nil)
+	req.Header.Set("Authorization", "Bearer "+*userToken)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func newMultipartImages(t *testing.T, contents ...[]byte) (*bytes.Buffer, string) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for i, content := range contents {
+		part, err := writer.CreateFormFile("images", fmt.Sprintf("image%d.png", i))
+		assert.NilError(t, err)
+		part.Write(content)
+	}
+	writer.Close()
+	return body, writer.FormDataContentType()
+}
+
+func TestUploadImages(t *testing.T) {
+	// テストのセットアップ
+	storageDir := t.TempDir()
+	t.Setenv("STORAGE_DIR", storageDir)
+	router := setup()
+
+	token := loginAs(t, router, 1)
+
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 800, 600)))
+
+	body, contentType := newMultipartImages(t, buf.Bytes(), buf.Bytes())
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/items/1/images", body)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", "Bearer "+*token)
+	router.ServeHTTP(w, req)
+
+	var res map[string][]models.ItemImage
+	json.Unmarshal([]byte(w.Body.String()), &res)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, 2, len(res["data"]))
+	assert.Equal(t, 0, res["data"][0].Position)
+	assert.Equal(t, 1, res["data"][1].Position)
+
+	// サムネイルは長辺が300pxに縮小される
+	thumbnail, err := os.Open(filepath.Join(storageDir, filepath.FromSlash(res["data"][0].ThumbnailKey)))
+	assert.NilError(t, err)
+	defer thumbnail.Close()
+	config, _, err := image.DecodeConfig(thumbnail)
+	assert.NilError(t, err)
+	assert.Equal(t, 300, config.Width)
+	assert.Equal(t, 225, config.Height)
+
+	// 表示順の並べ替え
+	reqBody, _ := json.Marshal(dto.ReorderItemImagesInput{ImageIDs: []uint{res["data"][1].ID, res["data"][0].ID}})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PUT", "/items/1/images/order", bytes.NewBuffer(reqBody))
+	req.Header.Set("Authorization", "Bearer "+*token)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// アイテムと一緒に並べ替え後の順序で返される
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/items/1", nil)
+	req.Header.Set("Authorization", "Bearer "+*token)
+	router.ServeHTTP(w, req)
+
+	var itemRes map[string]models.Item
+	json.Unmarshal([]byte(w.Body.String()), &itemRes)
+	assert.Equal(t, 2, len(itemRes["data"].Images))
+	assert.Equal(t, res["data"][1].ID, itemRes["data"].Images[0].ID)
+
+	// 画像以外のファイルは拒否される
+	body, contentType = newMultipartImages(t, []byte("not an image"))
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/items/1/images", body)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", "Bearer "+*token)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 1回に送れる枚数を超えると拒否される
+	images := make([][]byte, services.MaxImagesPerItem+1)
+	for i := range images {
+		images[i] = buf.Bytes()
+	}
+	body, contentType = newMultipartImages(t, images...)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/items/3/images", body)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", "Bearer "+*token)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// ファイルが小さくても、幅や高さが大きすぎる画像はデコードせずに拒否される
+	var wide bytes.Buffer
+	png.Encode(&wide, image.NewGray(image.Rect(0, 0, 8001, 1)))
+	body, contentType = newMultipartImages(t, wide.Bytes())
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/items/3/images", body)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", "Bearer "+*token)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	// 途中の画像のデコードに失敗した場合は、先に保存した画像も残さない
+	truncated := buf.Bytes()[:buf.Len()/2]
+	body, contentType = newMultipartImages(t, buf.Bytes(), truncated)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/items/3/images", body)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", "Bearer "+*token)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/items/3", nil)
+	router.ServeHTTP(w, req)
+	itemRes = map[string]models.Item{}
+	json.Unmarshal([]byte(w.Body.String()), &itemRes)
+	assert.Equal(t, 0, len(itemRes["data"].Images))
+	files, err := os.ReadDir(filepath.Join(storageDir, "items", "3"))
+	assert.NilError(t, err)
+	assert.Equal(t, 0, len(files))
+
+	// 同時にアップロードしても枚数の上限を超えず、表示順も重複しない
+	var small bytes.Buffer
+	png.Encode(&small, image.NewRGBA(image.Rect(0, 0, 10, 10)))
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		codes = map[int]int{}
+	)
+	for i := 0; i < 5; i++ {
+		body, contentType := newMultipartImages(t, small.Bytes(), small.Bytes(), small.Bytes(), small.Bytes())
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/items/3/images", body)
+			req.Header.Set("Content-Type", contentType)
+			req.Header.Set("Authorization", "Bearer "+*token)
+			router.ServeHTTP(w, req)
+			mu.Lock()
+			codes[w.Code]++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 2, codes[http.StatusCreated])
+	assert.Equal(t, 3, codes[http.StatusBadRequest])
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/items/3", nil)
+	router.ServeHTTP(w, req)
+	itemRes = map[string]models.Item{}
+	json.Unmarshal([]byte(w.Body.String()), &itemRes)
+	assert.Equal(t, 8, len(itemRes["data"].Images))
+	positions := map[int]bool{}
+	for _, itemImage := range itemRes["data"].Images {
+		positions[itemImage.Position] = true
+	}
+	assert.Equal(t, 8, len(positions))
+	files, err = os.ReadDir(filepath.Join(storageDir, "items", "3"))
+	assert.NilError(t, err)
+	assert.Equal(t, 16, len(files))
+}
+
+func TestFindPublicById(t *testing.T) {
+	// テストのセットアップ
+	router := setup()
+
+	// 認証なしで他人の出品を閲覧できる
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/items/1", nil)
+	router.ServeHTTP(w, req)
+
+	var res map[string]dto.ItemDetailOutput
+	json.Unmarshal([]byte(w.Body.String()), &res)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "テストアイテム1", res["data"].Name)
+	assert.Equal(t, uint(1), res["data"].Seller.ID)
+	assert.Equal(t, int64(2), res["data"].Seller.ListingCount)
+
+	// 下書きは公開されない
+	token := loginAs(t, router, 1)
+
+	status := models.ItemStatusDraft
+	reqBody, _ := json.Marshal(dto.UpdateItemInput{Status: &status})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PUT", "/items/1", bytes.NewBuffer(reqBody))
+	req.Header.Set("If-Match", `"1"`)
+	req.Header.Set("Authorization", "Bearer "+*token)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/items/1", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestFindMine(t *testing.T) {
+	// テストのセットアップ
+	router := setup()
+
+	token := loginAs(t, router, 1)
+
+	status := models.ItemStatusDraft
+	reqBody, _ := json.Marshal(dto.UpdateItemInput{Status: &status})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", "/items/3", bytes.NewBuffer(reqBody))
+	req.Header.Set("If-Match", `"1"`)
+	req.Header.Set("Authorization", "Bearer "+*token)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// 公開一覧には下書きが含まれない
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/items", nil)
+	router.ServeHTTP(w, req)
+
+	var res itemListResponse
+	json.Unmarshal([]byte(w.Body.String()), &res)
+	assert.Equal(t, int64(2), res.Total)
+
+	// 自分の出品一覧には下書きも含まれ、ステータスごとの件数が返される
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/me/items?sort=price_asc&limit=2", nil)
+	req.Header.Set("Authorization", "Bearer "+*token)
+	router.ServeHTTP(w, req)
+
+	var mine struct {
+		itemListResponse
+		StatusCounts map[string]int64 `json:"status_counts"`
+	}
+	json.Unmarshal([]byte(w.Body.String()), &mine)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int64(3), mine.Total)
+	assert.Equal(t, 2, len(mine.Data))
+	assert.Assert(t, mine.NextCursor != nil)
+	assert.Equal(t, int64(1), mine.StatusCounts[models.ItemStatusDraft])
+	assert.Equal(t, int64(1), mine.StatusCounts[models.ItemStatusListed])
+	assert.Equal(t, int64(1), mine.StatusCounts[models.ItemStatusSold])
+	assert.Equal(t, int64(0), mine.StatusCounts[models.ItemStatusCancelled])
+
+	// 認証が必要
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/me/items", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
 func TestCreate(t *testing.T) {
 	// テストのセットアップ
 	router := setup()
 
-	token, err := services.CreateToken(1, "test1@example.com")
-	assert.Equal(t, nil, err)
+	token := loginAs(t, router, 1)
 
 	createItemInput := dto.CreateItemInput{
 		Name:        "テストアイテム4",
@@ -144,8 +650,7 @@ func TestUpdate(t *testing.T) {
 	// テストのセットアップ
 	router := setup()
 
-	token, err := services.CreateToken(1, "test1@example.com")
-	assert.Equal(t, nil, err)
+	token := loginAs(t, router, 1)
 
 	description := "Updateテスト"
 	updateItemInput := dto.UpdateItemInput{
@@ -155,6 +660,7 @@ func TestUpdate(t *testing.T) {
 
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("PUT", "/items/1", bytes.NewBuffer(reqBody))
+	req.Header.Set("If-Match", `"1"`)
 	req.Header.Set("Authorization", "Bearer "+*token)
 
 	router.ServeHTTP(w, req)
@@ -166,29 +672,1366 @@ func TestUpdate(t *testing.T) {
 
 }
 
-func TestDelete(t *testing.T) {
+func TestUpdateStatus(t *testing.T) {
 	// テストのセットアップ
 	router := setup()
 
-	token, err := services.CreateToken(1, "test1@example.com")
-	assert.Equal(t, nil, err)
+	token := loginAs(t, router, 1)
 
-	w := httptest.NewRecorder()
-	req, _ := http.NewRequest("DELETE", "/items/1", nil)
-	req.Header.Set("Authorization", "Bearer "+*token)
+	updateStatus := func(itemId uint, status string) (int, models.Item) {
+		reqBody, _ := json.Marshal(dto.UpdateItemInput{Status: &status})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("PUT", fmt.Sprintf("/items/%d", itemId), bytes.NewBuffer(reqBody))
+		req.Header.Set("If-Match", `"1"`)
+		req.Header.Set("Authorization", "Bearer "+*token)
+		router.ServeHTTP(w, req)
+
+		var res map[string]models.Item
+		json.Unmarshal([]byte(w.Body.String()), &res)
+		return w.Code, res["data"]
+	}
+
+	// 出品中から発送済みへは直接遷移できない
+	code, _ := updateStatus(1, models.ItemStatusShipped)
+	assert.Equal(t, http.StatusConflict, code)
 
+	// 取引中・売り切れのステータスは注文やオファーだけが変更する
+	code, _ = updateStatus(3, models.ItemStatusSold)
+	assert.Equal(t, http.StatusConflict, code)
+	code, _ = updateStatus(1, models.ItemStatusReserved)
+	assert.Equal(t, http.StatusConflict, code)
+
+	code, item := updateStatus(3, models.ItemStatusDraft)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, models.ItemStatusDraft, item.Status)
+
+	// 売り切れ済みのアイテムを出品中に戻したり取り消したりすることはできない
+	code, _ = updateStatus(2, models.ItemStatusListed)
+	assert.Equal(t, http.StatusConflict, code)
+	code, _ = updateStatus(2, models.ItemStatusCancelled)
+	assert.Equal(t, http.StatusConflict, code)
+
+	// ステータスで絞り込み(下書きは公開されない)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/items?status=listed&status=sold", nil)
 	router.ServeHTTP(w, req)
-	var res map[string]models.Item
-	json.Unmarshal([]byte(w.Body.String()), &res)
 
+	var res itemListResponse
+	json.Unmarshal([]byte(w.Body.String()), &res)
 	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int64(2), res.Total)
+}
 
-	w = httptest.NewRecorder()
-	req, _ = http.NewRequest("GET", "/items/1", nil)
+func TestItemInTrade(t *testing.T) {
+	// テストのセットアップ
+	router := setup()
+
+	purchaseAndPay(t, router, 1, 2)
+
+	// 支払い済みの注文があるアイテムは出品者が取り消したり削除したりできない
+	status := models.ItemStatusCancelled
+	w := httptest.NewRecorder()
+	reqBody, _ := json.Marshal(dto.UpdateItemInput{Status: &status})
+	token := loginAs(t, router, 1)
+	req, _ := http.NewRequest("PUT", "/items/1", bytes.NewBuffer(reqBody))
+	req.Header.Set("If-Match", "*")
 	req.Header.Set("Authorization", "Bearer "+*token)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
 
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/items/1", nil)
+	req.Header.Set("If-Match", "*")
+	req.Header.Set("Authorization", "Bearer "+*token)
 	router.ServeHTTP(w, req)
-	json.Unmarshal([]byte(w.Body.String()), &res)
+	assert.Equal(t, http.StatusConflict, w.Code)
 
-	assert.Equal(t, http.StatusNotFound, w.Code)
+	// 二重に購入されることはない
+	w = requestAs(t, router, "POST", "/items/1/purchase", 3, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestDelete(t *testing.T) {
+	// テストのセットアップ
+	router := setup()
+
+	token := loginAs(t, router, 1)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/items/1", nil)
+	req.Header.Set("If-Match", `"1"`)
+	req.Header.Set("Authorization", "Bearer "+*token)
+
+	router.ServeHTTP(w, req)
+	var res map[string]models.Item
+	json.Unmarshal([]byte(w.Body.String()), &res)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/items/1", nil)
+	req.Header.Set("Authorization", "Bearer "+*token)
+
+	router.ServeHTTP(w, req)
+	json.Unmarshal([]byte(w.Body.String()), &res)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestTrashAndRestore(t *testing.T) {
+	// テストのセットアップ
+	router := setup()
+
+	token := loginAs(t, router, 1)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/items/1", nil)
+	req.Header.Set("If-Match", `"1"`)
+	req.Header.Set("Authorization", "Bearer "+*token)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// 削除したアイテムはゴミ箱に表示される
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/me/items/trash", nil)
+	req.Header.Set("Authorization", "Bearer "+*token)
+	router.ServeHTTP(w, req)
+
+	var res itemListResponse
+	json.Unmarshal([]byte(w.Body.String()), &res)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int64(1), res.Total)
+	assert.Equal(t, uint(1), res.Data[0].ID)
+
+	// 復元
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/items/1/restore", nil)
+	req.Header.Set("Authorization", "Bearer "+*token)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/items/1", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// ゴミ箱にないアイテムは復元できない
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/items/1/restore", nil)
+	req.Header.Set("Authorization", "Bearer "+*token)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestImportAndExport(t *testing.T) {
+	// テストのセットアップ
+	router := setup()
+
+	token := loginAs(t, router, 1)
+
+	csvBody := "name,price,description\n" +
+		"インポート商品,1200,\"説明, カンマ入り\"\n" +
+		"価格不正,abc,\n" +
+		"a,500,名前が短い\n"
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/me/items/import", strings.NewReader(csvBody))
+	req.Header.Set("Content-Type", "text/csv")
+	req.Header.Set("Authorization", "Bearer "+*token)
+	router.ServeHTTP(w, req)
+
+	var res map[string]dto.ImportItemsOutput
+	json.Unmarshal([]byte(w.Body.String()), &res)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, res["data"].Created)
+	assert.Equal(t, 2, res["data"].Failed)
+	assert.Equal(t, 2, res["data"].Results[0].Row)
+	assert.Assert(t, res["data"].Results[0].ItemID != nil)
+	assert.Equal(t, 3, res["data"].Results[1].Row)
+	assert.Assert(t, len(res["data"].Results[2].Errors) > 0)
+
+	// 必須の列がないCSVは拒否される
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/me/items/import", strings.NewReader("title\nfoo\n"))
+	req.Header.Set("Content-Type", "text/csv")
+	req.Header.Set("Authorization", "Bearer "+*token)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// CSVでエクスポート
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/me/items/export", nil)
+	req.Header.Set("Authorization", "Bearer "+*token)
+	router.ServeHTTP(w, req)
+
+	exported := w.Body.String()
+	records, err := csv.NewReader(strings.NewReader(exported)).ReadAll()
+	assert.NilError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 5, len(records))
+	assert.Equal(t, "インポート商品", records[1][1])
+	assert.Equal(t, "説明, カンマ入り", records[1][3])
+
+	// JSONでエクスポート
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/me/items/export?format=json", nil)
+	req.Header.Set("Authorization", "Bearer "+*token)
+	router.ServeHTTP(w, req)
+
+	var rows []dto.ExportItemRow
+	err = json.Unmarshal([]byte(w.Body.String()), &rows)
+	assert.NilError(t, err)
+	assert.Equal(t, 4, len(rows))
+
+	// エクスポートしたCSVはそのまま取り込め、売り切れのアイテムは下書きとして登録される
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/me/items/import", strings.NewReader(exported))
+	req.Header.Set("Content-Type", "text/csv")
+	req.Header.Set("Authorization", "Bearer "+*token)
+	router.ServeHTTP(w, req)
+
+	res = map[string]dto.ImportItemsOutput{}
+	json.Unmarshal([]byte(w.Body.String()), &res)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 4, res["data"].Created)
+	assert.Equal(t, 0, res["data"].Failed)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/me/items?status=draft", nil)
+	req.Header.Set("Authorization", "Bearer "+*token)
+	router.ServeHTTP(w, req)
+
+	var draftRes itemListResponse
+	json.Unmarshal([]byte(w.Body.String()), &draftRes)
+	assert.Equal(t, int64(1), draftRes.Total)
+	assert.Equal(t, "テストアイテム2", draftRes.Data[0].Name)
+}
+
+func TestOptimisticConcurrency(t *testing.T) {
+	// テストのセットアップ
+	router := setup()
+
+	token := loginAs(t, router, 1)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/items/1", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, `"1"`, w.Header().Get("ETag"))
+
+	update := func(ifMatch string) *httptest.ResponseRecorder {
+		description := "同時更新テスト"
+		reqBody, _ := json.Marshal(dto.UpdateItemInput{Description: &description})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("PUT", "/items/1", bytes.NewBuffer(reqBody))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		req.Header.Set("Authorization", "Bearer "+*token)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// If-Match がない更新は拒否される
+	w = update("")
+	assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+
+	// 更新するとバージョンが進む
+	w = update(`"1"`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+
+	// 古いバージョンでの更新や削除は拒否される
+	w = update(`"1"`)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/items/1", nil)
+	req.Header.Set("If-Match", `"1"`)
+	req.Header.Set("Authorization", "Bearer "+*token)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/items/1", nil)
+	req.Header.Set("If-Match", `"2"`)
+	req.Header.Set("Authorization", "Bearer "+*token)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestPurchase(t *testing.T) {
+	// テストのセットアップ
+	router := setup()
+
+	tokens := map[uint]*string{}
+	for userId := uint(1); userId <= 3; userId++ {
+		tokens[userId] = loginAs(t, router, userId)
+	}
+	purchase := func(itemId uint, userId uint) *httptest.ResponseRecorder {
+		token := tokens[userId]
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", fmt.Sprintf("/items/%d/purchase", itemId), nil)
+		req.Header.Set("Authorization", "Bearer "+*token)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// 自分のアイテムは購入できない
+	w := purchase(1, 1)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// 売り切れのアイテムは購入できない
+	w = purchase(2, 2)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// 同時に購入しても成功するのは1人だけ
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		codes = map[int]int{}
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w := purchase(1, uint(2+i%2))
+			mu.Lock()
+			codes[w.Code]++
+			mu.Unlock()
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, 1, codes[http.StatusCreated])
+	assert.Equal(t, 9, codes[http.StatusConflict])
+
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/items/1", nil)
+	router.ServeHTTP(w, req)
+
+	var res map[string]dto.ItemDetailOutput
+	json.Unmarshal([]byte(w.Body.String()), &res)
+	assert.Equal(t, models.ItemStatusSold, res["data"].Status)
+	assert.Equal(t, uint(2), res["data"].Version)
+
+	// 購入すると注文が作成される
+	w = purchase(3, 3)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var orderRes map[string]models.Order
+	json.Unmarshal([]byte(w.Body.String()), &orderRes)
+	assert.Equal(t, uint(3), orderRes["data"].ItemID)
+	assert.Equal(t, uint(3), orderRes["data"].BuyerID)
+	assert.Equal(t, uint(1), orderRes["data"].SellerID)
+	assert.Equal(t, uint(3000), orderRes["data"].Price)
+	assert.Equal(t, models.OrderStatusPendingPayment, orderRes["data"].Status)
+}
+
+// loginAs は userId のテスト用ユーザーでログインし、発行されたアクセストークンを返します
+func loginAs(t *testing.T, router *gin.Engine, userId uint) *string {
+	reqBody, _ := json.Marshal(dto.LoginInput{
+		Email:    fmt.Sprintf("test%d@example.com", userId),
+		Password: fmt.Sprintf("test%dpass", userId),
+	})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/auth/login", bytes.NewBuffer(reqBody))
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var tokens dto.TokenOutput
+	json.Unmarshal(w.Body.Bytes(), &tokens)
+	return &tokens.Token
+}
+
+// requestAs は userId のユーザーとしてAPIリクエストを実行します
+func requestAs(t *testing.T, router *gin.Engine, method string, path string, userId uint, body any) *httptest.ResponseRecorder {
+	token := loginAs(t, router, userId)
+
+	reqBody := bytes.NewBuffer(nil)
+	if body != nil {
+		b, _ := json.Marshal(body)
+		reqBody = bytes.NewBuffer(b)
+	}
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, reqBody)
+	req.Header.Set("Authorization", "Bearer "+*token)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// sendPaymentEvent は署名付きの決済イベントを Webhook に送ります
+func sendPaymentEvent(router *gin.Engine, event payment.Event, signature string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(event)
+	if signature == "" {
+		signature = payment.Sign(os.Getenv("PAYMENT_WEBHOOK_SECRET"), body)
+	}
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/webhooks/payments", bytes.NewBuffer(body))
+	req.Header.Set(payment.SignatureHeader, signature)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// purchaseAndPay は userId のユーザーとしてアイテムを購入し、支払いまで済ませた注文を返します
+func purchaseAndPay(t *testing.T, router *gin.Engine, itemId uint, userId uint) models.Order {
+	var res map[string]models.Order
+	w := requestAs(t, router, "POST", fmt.Sprintf("/items/%d/purchase", itemId), userId, nil)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	json.Unmarshal([]byte(w.Body.String()), &res)
+	orderId := res["data"].ID
+
+	w = requestAs(t, router, "POST", fmt.Sprintf("/orders/%d/pay", orderId), userId, nil)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	json.Unmarshal([]byte(w.Body.String()), &res)
+
+	event := payment.Event{
+		ID:        fmt.Sprintf("evt_order_%d", orderId),
+		Type:      payment.EventPaymentAuthorized,
+		PaymentID: res["data"].PaymentID,
+		Reference: fmt.Sprint(orderId),
+	}
+	w = sendPaymentEvent(router, event, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	return res["data"]
+}
+
+func TestPayment(t *testing.T) {
+	// テストのセットアップ
+	router := setup()
+
+	findOrder := func(orderId uint) models.Order {
+		w := requestAs(t, router, "GET", fmt.Sprintf("/orders/%d", orderId), 2, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var res map[string]models.Order
+		json.Unmarshal([]byte(w.Body.String()), &res)
+		return res["data"]
+	}
+
+	w := requestAs(t, router, "POST", "/items/1/purchase", 2, nil)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// 購入者以外は支払えない
+	w = requestAs(t, router, "POST", "/orders/1/pay", 3, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = requestAs(t, router, "POST", "/orders/1/pay", 2, nil)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+
+	var res map[string]models.Order
+	json.Unmarshal([]byte(w.Body.String()), &res)
+	paymentId := res["data"].PaymentID
+	assert.Assert(t, paymentId != "")
+
+	// 二重に支払うことはできない
+	w = requestAs(t, router, "POST", "/orders/1/pay", 2, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// 署名が正しくないイベントは拒否される
+	event := payment.Event{ID: "evt_1", Type: payment.EventPaymentAuthorized, PaymentID: paymentId, Reference: "1"}
+	w = sendPaymentEvent(router, event, "invalid")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, models.OrderStatusPendingPayment, findOrder(1).Status)
+
+	// 与信のイベントで支払い済みになる
+	w = sendPaymentEvent(router, event, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, models.OrderStatusPaid, findOrder(1).Status)
+
+	// 同じイベントが再送されても結果は変わらない
+	w = sendPaymentEvent(router, event, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = sendPaymentEvent(router, payment.Event{ID: "evt_2", Type: payment.EventPaymentFailed, PaymentID: paymentId, Reference: "1"}, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, models.OrderStatusPaid, findOrder(1).Status)
+
+	// 支払いに失敗するとアイテムは出品中に戻る
+	w = requestAs(t, router, "POST", "/items/3/purchase", 2, nil)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w = requestAs(t, router, "POST", "/orders/2/pay", 2, nil)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	json.Unmarshal([]byte(w.Body.String()), &res)
+
+	w = sendPaymentEvent(router, payment.Event{ID: "evt_3", Type: payment.EventPaymentFailed, PaymentID: res["data"].PaymentID, Reference: "2"}, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, models.OrderStatusFailed, findOrder(2).Status)
+	assert.Equal(t, models.ItemStatusListed, findItemStatus(router, 3))
+}
+
+func findItemStatus(router *gin.Engine, itemId uint) string {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/items/%d", itemId), nil)
+	router.ServeHTTP(w, req)
+
+	var res map[string]dto.ItemDetailOutput
+	json.Unmarshal([]byte(w.Body.String()), &res)
+	return res["data"].Status
+}
+
+func TestOrderWorkflow(t *testing.T) {
+	// テストのセットアップ
+	router := setup()
+
+	order := purchaseAndPay(t, router, 1, 2)
+	path := fmt.Sprintf("/orders/%d", order.ID)
+
+	var res map[string]models.Order
+
+	// 発送できるのは出品者のみ
+	w := requestAs(t, router, "POST", path+"/ship", 2, dto.ShipOrderInput{TrackingNumber: "1234-5678"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = requestAs(t, router, "POST", path+"/ship", 1, dto.ShipOrderInput{})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = requestAs(t, router, "POST", path+"/ship", 1, dto.ShipOrderInput{TrackingNumber: "1234-5678"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal([]byte(w.Body.String()), &res)
+	assert.Equal(t, models.OrderStatusShipped, res["data"].Status)
+	assert.Equal(t, "1234-5678", res["data"].TrackingNumber)
+	assert.Equal(t, models.ItemStatusShipped, findItemStatus(router, 1))
+
+	// 受取確認できるのは購入者のみ
+	w = requestAs(t, router, "POST", path+"/receive", 1, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = requestAs(t, router, "POST", path+"/receive", 2, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// 取引の当事者以外は評価できない
+	w = requestAs(t, router, "POST", path+"/rate", 3, dto.RateOrderInput{Rating: models.RatingGood})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// 片方の評価だけでは完了しない
+	w = requestAs(t, router, "POST", path+"/rate", 2, dto.RateOrderInput{Rating: models.RatingGood, Comment: "ありがとうございました"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal([]byte(w.Body.String()), &res)
+	assert.Equal(t, models.OrderStatusReceived, res["data"].Status)
+
+	w = requestAs(t, router, "POST", path+"/rate", 2, dto.RateOrderInput{Rating: models.RatingBad})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// 双方が評価すると取引完了
+	w = requestAs(t, router, "POST", path+"/rate", 1, dto.RateOrderInput{Rating: models.RatingNormal})
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal([]byte(w.Body.String()), &res)
+	assert.Equal(t, models.OrderStatusCompleted, res["data"].Status)
+	assert.Assert(t, res["data"].CompletedAt != nil)
+	assert.Equal(t, models.ItemStatusCompleted, findItemStatus(router, 1))
+
+	// 完了後は取り消せない
+	w = requestAs(t, router, "POST", path+"/cancel", 1, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// 誰がいつ何をしたかが記録される
+	w = requestAs(t, router, "GET", path+"/events", 1, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var eventRes map[string][]models.OrderEvent
+	json.Unmarshal([]byte(w.Body.String()), &eventRes)
+	var actions []string
+	for _, event := range eventRes["data"] {
+		actions = append(actions, event.Action)
+	}
+	assert.DeepEqual(t, []string{
+		models.OrderActionPurchase,
+		models.OrderActionPaymentAuthorized,
+		models.OrderActionShip,
+		models.OrderActionReceive,
+		models.OrderActionRate,
+		models.OrderActionRate,
+		models.OrderActionComplete,
+	}, actions)
+	assert.Assert(t, eventRes["data"][1].ActorID == nil)
+	assert.Equal(t, uint(1), *eventRes["data"][2].ActorID)
+	assert.Equal(t, models.OrderStatusCapturing, eventRes["data"][5].ToStatus)
+	assert.Assert(t, eventRes["data"][6].ActorID == nil)
+	assert.Equal(t, models.OrderStatusCompleted, eventRes["data"][6].ToStatus)
+
+	w = requestAs(t, router, "GET", path+"/events", 3, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestCancelOrder(t *testing.T) {
+	// テストのセットアップ
+	router := setup()
+
+	// 支払い前は購入者も取り消せる
+	w := requestAs(t, router, "POST", "/items/3/purchase", 2, nil)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w = requestAs(t, router, "POST", "/orders/1/cancel", 2, dto.CancelOrderInput{Reason: "間違えて購入しました"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var res map[string]models.Order
+	json.Unmarshal([]byte(w.Body.String()), &res)
+	assert.Equal(t, models.OrderStatusCancelled, res["data"].Status)
+	assert.Equal(t, models.ItemStatusListed, findItemStatus(router, 3))
+
+	// 支払い後は出品者のみが取り消せる
+	order := purchaseAndPay(t, router, 3, 2)
+	path := fmt.Sprintf("/orders/%d/cancel", order.ID)
+	w = requestAs(t, router, "POST", path, 2, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = requestAs(t, router, "POST", path, 1, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, models.ItemStatusListed, findItemStatus(router, 3))
+}
+
+func TestWallet(t *testing.T) {
+	// テストのセットアップ
+	router := setup()
+
+	// 取引が完了すると販売手数料を差し引いた額が売上になる
+	order := purchaseAndPay(t, router, 1, 2)
+	path := fmt.Sprintf("/orders/%d", order.ID)
+	w := requestAs(t, router, "POST", path+"/ship", 1, dto.ShipOrderInput{TrackingNumber: "1234-5678"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = requestAs(t, router, "POST", path+"/receive", 2, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = requestAs(t, router, "POST", path+"/rate", 2, dto.RateOrderInput{Rating: models.RatingGood})
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = requestAs(t, router, "POST", path+"/rate", 1, dto.RateOrderInput{Rating: models.RatingGood})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	findWallet := func() dto.WalletOutput {
+		w := requestAs(t, router, "GET", "/me/wallet", 1, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var res map[string]dto.WalletOutput
+		json.Unmarshal([]byte(w.Body.String()), &res)
+		return res["data"]
+	}
+	assert.Equal(t, dto.WalletOutput{Available: 900, Locked: 0}, findWallet())
+
+	w = requestAs(t, router, "GET", "/me/wallet/transactions", 1, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var entryRes struct {
+		Data  []models.LedgerEntry `json:"data"`
+		Total int64                `json:"total"`
+	}
+	json.Unmarshal([]byte(w.Body.String()), &entryRes)
+	assert.Equal(t, int64(2), entryRes.Total)
+	assert.Equal(t, models.LedgerEntryTypeFee, entryRes.Data[0].Type)
+	assert.Equal(t, int64(-100), entryRes.Data[0].Amount)
+	assert.Equal(t, models.LedgerEntryTypeSale, entryRes.Data[1].Type)
+	assert.Equal(t, int64(1000), entryRes.Data[1].Amount)
+
+	// 残高を超える出金は申請できない
+	w = requestAs(t, router, "POST", "/me/wallet/payouts", 1, dto.CreatePayoutInput{Amount: 1000})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// 申請した額は支払い済みになるまで引き出せない
+	w = requestAs(t, router, "POST", "/me/wallet/payouts", 1, dto.CreatePayoutInput{Amount: 500})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var payoutRes map[string]models.Payout
+	json.Unmarshal([]byte(w.Body.String()), &payoutRes)
+	payoutPath := fmt.Sprintf("/admin/payouts/%d/paid", payoutRes["data"].ID)
+	assert.Equal(t, dto.WalletOutput{Available: 400, Locked: 500}, findWallet())
+
+	// 支払い済みにできるのは管理者のみ
+	w = requestAs(t, router, "POST", payoutPath, 1, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = requestAs(t, router, "GET", "/admin/payouts?status=requested", 2, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var payoutListRes struct {
+		Data  []models.Payout `json:"data"`
+		Total int64           `json:"total"`
+	}
+	json.Unmarshal([]byte(w.Body.String()), &payoutListRes)
+	assert.Equal(t, int64(1), payoutListRes.Total)
+
+	w = requestAs(t, router, "POST", payoutPath, 2, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = requestAs(t, router, "POST", payoutPath, 2, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, dto.WalletOutput{Available: 400, Locked: 0}, findWallet())
+}
+
+func TestReviews(t *testing.T) {
+	// テストのセットアップ
+	router := setup()
+
+	// 2件の取引を完了させる。出品者のユーザー1は good と bad を受け取る
+	for _, rating := range []struct {
+		itemId  uint
+		buyerId uint
+		rating  string
+	}{{1, 2, models.RatingGood}, {3, 3, models.RatingBad}} {
+		order := purchaseAndPay(t, router, rating.itemId, rating.buyerId)
+		path := fmt.Sprintf("/orders/%d", order.ID)
+		w := requestAs(t, router, "POST", path+"/ship", 1, dto.ShipOrderInput{TrackingNumber: "1234-5678"})
+		assert.Equal(t, http.StatusOK, w.Code)
+		w = requestAs(t, router, "POST", path+"/receive", rating.buyerId, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		w = requestAs(t, router, "POST", path+"/rate", rating.buyerId, dto.RateOrderInput{Rating: rating.rating, Comment: "コメント"})
+		assert.Equal(t, http.StatusOK, w.Code)
+		w = requestAs(t, router, "POST", path+"/rate", 1, dto.RateOrderInput{Rating: models.RatingGood})
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	var res struct {
+		Data       []dto.ReviewOutput    `json:"data"`
+		Total      int64                 `json:"total"`
+		Reputation dto.ReputationSummary `json:"reputation"`
+	}
+
+	// 認証なしで受け取った評価を新しい順に閲覧できる
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/users/1/reviews", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal([]byte(w.Body.String()), &res)
+	assert.Equal(t, int64(2), res.Total)
+	assert.Equal(t, models.RatingBad, res.Data[0].Rating)
+	assert.Equal(t, uint(3), res.Data[0].RaterID)
+	assert.Equal(t, "buyer", res.Data[0].RaterRole)
+	assert.Equal(t, "コメント", res.Data[0].Comment)
+	assert.DeepEqual(t, dto.ReputationSummary{Good: 1, Normal: 0, Bad: 1, Total: 2}, res.Reputation)
+
+	// 評価で絞り込み
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/users/1/reviews?rating=good", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal([]byte(w.Body.String()), &res)
+	assert.Equal(t, int64(1), res.Total)
+	assert.Equal(t, uint(2), res.Data[0].RaterID)
+
+	// 購入者として受け取った評価
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/users/2/reviews?role=buyer", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal([]byte(w.Body.String()), &res)
+	assert.Equal(t, int64(1), res.Total)
+	assert.Equal(t, "seller", res.Data[0].RaterRole)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/users/2/reviews?role=seller", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal([]byte(w.Body.String()), &res)
+	assert.Equal(t, int64(0), res.Total)
+
+	// 出品の詳細に出品者の評価が含まれる
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/items/1", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var itemRes map[string]dto.ItemDetailOutput
+	json.Unmarshal([]byte(w.Body.String()), &itemRes)
+	assert.Equal(t, int64(2), itemRes["data"].Seller.Reputation.Total)
+
+	// 存在しないユーザーと不正な絞り込み
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/users/99/reviews", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/users/1/reviews?rating=excellent", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestMessages(t *testing.T) {
+	// テストのセットアップ
+	router := setup()
+
+	w := requestAs(t, router, "POST", "/items/1/purchase", 2, nil)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	path := "/orders/1/messages"
+
+	// 取引の当事者以外はメッセージを送れない
+	w = requestAs(t, router, "POST", path, 3, dto.SendMessageInput{Body: "こんにちは"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = requestAs(t, router, "POST", path, 2, dto.SendMessageInput{})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	for _, body := range []string{"購入しました", "発送はいつ頃になりますか"} {
+		w = requestAs(t, router, "POST", path, 2, dto.SendMessageInput{Body: body})
+		assert.Equal(t, http.StatusCreated, w.Code)
+	}
+	w = requestAs(t, router, "POST", path, 1, dto.SendMessageInput{Body: "明日発送します"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var res struct {
+		Data   []models.OrderMessage `json:"data"`
+		Total  int64                 `json:"total"`
+		Unread int64                 `json:"unread"`
+	}
+
+	// 新しい順にページングして取得できる
+	w = requestAs(t, router, "GET", path+"?limit=2", 1, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal([]byte(w.Body.String()), &res)
+	assert.Equal(t, int64(3), res.Total)
+	assert.Equal(t, int64(2), res.Unread)
+	assert.Equal(t, 2, len(res.Data))
+	assert.Equal(t, "明日発送します", res.Data[0].Body)
+	assert.Equal(t, uint(2), res.Data[0].RecipientID)
+
+	w = requestAs(t, router, "GET", path, 3, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// 未読件数は受信者ごとに数える
+	var unreadRes map[string]dto.UnreadMessagesOutput
+	w = requestAs(t, router, "GET", "/me/messages/unread", 1, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal([]byte(w.Body.String()), &unreadRes)
+	assert.Equal(t, int64(2), unreadRes["data"].Total)
+	assert.DeepEqual(t, []dto.UnreadCount{{OrderID: 1, Count: 2}}, unreadRes["data"].Orders)
+
+	w = requestAs(t, router, "GET", "/me/messages/unread", 2, nil)
+	json.Unmarshal([]byte(w.Body.String()), &unreadRes)
+	assert.Equal(t, int64(1), unreadRes["data"].Total)
+
+	// 既読にすると送信者にも既読日時が見える
+	w = requestAs(t, router, "POST", path+"/read", 1, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"data":{"read":2}}`, w.Body.String())
+
+	w = requestAs(t, router, "GET", "/me/messages/unread", 1, nil)
+	json.Unmarshal([]byte(w.Body.String()), &unreadRes)
+	assert.Equal(t, int64(0), unreadRes["data"].Total)
+
+	w = requestAs(t, router, "GET", path, 2, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal([]byte(w.Body.String()), &res)
+	assert.Equal(t, int64(1), res.Unread)
+	assert.Assert(t, res.Data[0].ReadAt == nil)
+	assert.Assert(t, res.Data[1].ReadAt != nil)
+	assert.Assert(t, res.Data[2].ReadAt != nil)
+
+	// 認証が必要
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", path, nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestItemComments(t *testing.T) {
+	// テストのセットアップ
+	router := setup()
+
+	var res map[string]models.ItemComment
+
+	// ログインしていれば誰でも質問でき、出品者にお知らせが届く
+	w := requestAs(t, router, "POST", "/items/1/comments", 3, dto.CreateItemCommentInput{Body: "値下げは可能ですか"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	json.Unmarshal([]byte(w.Body.String()), &res)
+	questionId := res["data"].ID
+
+	w = requestAs(t, router, "POST", "/items/1/comments", 3, dto.CreateItemCommentInput{})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var notificationRes struct {
+		Data   []models.Notification `json:"data"`
+		Total  int64                 `json:"total"`
+		Unread int64                 `json:"unread"`
+	}
+	w = requestAs(t, router, "GET", "/me/notifications", 1, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal([]byte(w.Body.String()), &notificationRes)
+	assert.Equal(t, int64(1), notificationRes.Unread)
+	assert.Equal(t, models.NotificationTypeItemQuestion, notificationRes.Data[0].Type)
+	assert.Equal(t, questionId, *notificationRes.Data[0].CommentID)
+
+	// 回答できるのは出品者のみ
+	w = requestAs(t, router, "POST", "/items/1/comments", 2, dto.CreateItemCommentInput{Body: "横から失礼します", ParentID: &questionId})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = requestAs(t, router, "POST", "/items/1/comments", 1, dto.CreateItemCommentInput{Body: "900円までなら可能です", ParentID: &questionId})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	json.Unmarshal([]byte(w.Body.String()), &res)
+	answerId := res["data"].ID
+
+	w = requestAs(t, router, "POST", "/items/1/comments", 1, dto.CreateItemCommentInput{Body: "回答への回答", ParentID: &answerId})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// 自分の回答は出品者へのお知らせにならない
+	w = requestAs(t, router, "POST", "/me/notifications/read", 1, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = requestAs(t, router, "GET", "/me/notifications", 1, nil)
+	json.Unmarshal([]byte(w.Body.String()), &notificationRes)
+	assert.Equal(t, int64(1), notificationRes.Total)
+	assert.Equal(t, int64(0), notificationRes.Unread)
+
+	// 認証なしで投稿順にページングして取得できる
+	var listRes struct {
+		Data  []models.ItemComment `json:"data"`
+		Total int64                `json:"total"`
+	}
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/items/1/comments?limit=1&offset=1", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal([]byte(w.Body.String()), &listRes)
+	assert.Equal(t, int64(2), listRes.Total)
+	assert.Equal(t, answerId, listRes.Data[0].ID)
+	assert.Equal(t, questionId, *listRes.Data[0].ParentID)
+
+	// 削除できるのは出品者と管理者のみ。質問を消すと回答も消える
+	path := fmt.Sprintf("/items/1/comments/%d", questionId)
+	w = requestAs(t, router, "DELETE", path, 3, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = requestAs(t, router, "DELETE", path, 2, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = requestAs(t, router, "DELETE", path, 1, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/items/1/comments", nil)
+	router.ServeHTTP(w, req)
+	json.Unmarshal([]byte(w.Body.String()), &listRes)
+	assert.Equal(t, int64(0), listRes.Total)
+
+	// 購入済みのアイテムにはコメントできない
+	w = requestAs(t, router, "POST", "/items/2/comments", 3, dto.CreateItemCommentInput{Body: "まだありますか"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// 認証が必要
+	reqBody, _ := json.Marshal(dto.CreateItemCommentInput{Body: "質問です"})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/items/1/comments", bytes.NewBuffer(reqBody))
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestLikes(t *testing.T) {
+	// テストのセットアップ
+	router := setup()
+
+	var res map[string]dto.LikeOutput
+
+	// 同時にいいねしても、ユーザーごとに1件だけ数える
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(userId uint) {
+			defer wg.Done()
+			w := requestAs(t, router, "POST", "/items/1/like", userId, nil)
+			assert.Equal(t, http.StatusOK, w.Code)
+		}(uint(i%2 + 2))
+	}
+	wg.Wait()
+
+	w := requestAs(t, router, "POST", "/items/1/like", 2, nil)
+	json.Unmarshal([]byte(w.Body.String()), &res)
+	assert.DeepEqual(t, dto.LikeOutput{ItemID: 1, Liked: true, LikeCount: 2}, res["data"])
+
+	// アイテムのレスポンスにいいね数が含まれる
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/items/1", nil)
+	router.ServeHTTP(w, req)
+	var itemRes map[string]dto.ItemDetailOutput
+	json.Unmarshal([]byte(w.Body.String()), &itemRes)
+	assert.Equal(t, uint(2), itemRes["data"].LikeCount)
+
+	// アイテムを更新してもいいね数は巻き戻らない
+	name := "更新後のアイテム"
+	reqBody, _ := json.Marshal(dto.UpdateItemInput{Name: &name})
+	token := loginAs(t, router, 1)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PUT", "/items/1", bytes.NewBuffer(reqBody))
+	req.Header.Set("If-Match", `"1"`)
+	req.Header.Set("Authorization", "Bearer "+*token)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// 取り消しても何度取り消しても件数は合う
+	for i := 0; i < 2; i++ {
+		w = requestAs(t, router, "DELETE", "/items/1/like", 3, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		json.Unmarshal([]byte(w.Body.String()), &res)
+		assert.DeepEqual(t, dto.LikeOutput{ItemID: 1, Liked: false, LikeCount: 1}, res["data"])
+	}
+
+	// 存在しないアイテムにはいいねできない
+	w = requestAs(t, router, "POST", "/items/99/like", 2, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// 購入済みや削除済みになったアイテムはフラグが立つ
+	for _, itemId := range []uint{2, 3} {
+		w = requestAs(t, router, "POST", fmt.Sprintf("/items/%d/like", itemId), 2, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/items/3", nil)
+	req.Header.Set("If-Match", `"1"`)
+	req.Header.Set("Authorization", "Bearer "+*token)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var likesRes struct {
+		Data  []dto.LikedItemOutput `json:"data"`
+		Total int64                 `json:"total"`
+	}
+	w = requestAs(t, router, "GET", "/me/likes", 2, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal([]byte(w.Body.String()), &likesRes)
+	assert.Equal(t, int64(3), likesRes.Total)
+	assert.Equal(t, uint(3), likesRes.Data[0].Item.ID)
+	assert.Assert(t, likesRes.Data[0].Deleted)
+	assert.Equal(t, "", likesRes.Data[0].Item.Name)
+	assert.Equal(t, uint(2), likesRes.Data[1].Item.ID)
+	assert.Assert(t, likesRes.Data[1].SoldOut)
+	assert.Assert(t, !likesRes.Data[1].Deleted)
+	assert.Equal(t, uint(1), likesRes.Data[2].Item.ID)
+	assert.Assert(t, !likesRes.Data[2].SoldOut)
+	assert.Equal(t, "更新後のアイテム", likesRes.Data[2].Item.Name)
+	assert.Equal(t, uint(1), likesRes.Data[2].Item.LikeCount)
+
+	// 削除されたアイテムのいいねも取り消せる
+	w = requestAs(t, router, "DELETE", "/items/3/like", 2, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = requestAs(t, router, "GET", "/me/likes", 2, nil)
+	json.Unmarshal([]byte(w.Body.String()), &likesRes)
+	assert.Equal(t, int64(2), likesRes.Total)
+}
+
+func TestLogout(t *testing.T) {
+	// テストのセットアップ
+	router := setup()
+
+	request := func(method string, path string, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, http.NoBody)
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(w, req)
+		return w
+	}
+	first := loginAs(t, router, 1)
+	second := loginAs(t, router, 1)
+
+	// ログアウトしたトークンだけが使えなくなる
+	w := request("POST", "/auth/logout", *first)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = request("GET", "/me/items", *first)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = request("GET", "/me/items", *second)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// 全端末からログアウトすると他のトークンも使えなくなる
+	w = request("POST", "/auth/logout-all", *second)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = request("GET", "/me/items", *second)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// 他のユーザーには影響しない
+	w = requestAs(t, router, "GET", "/me/items", 2, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/auth/logout", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// jti のないトークンはログアウトできない
+	noJTI, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":   2,
+		"email": "test2@example.com",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte(os.Getenv("SECRET_KEY")))
+	assert.Equal(t, nil, err)
+	w = request("GET", "/me/items", noJTI)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = request("POST", "/auth/logout", noJTI)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestEmailVerification(t *testing.T) {
+	// テストのセットアップ
+	router := setup()
+
+	signupInput := dto.SignupInput{Email: "test4@example.com", Password: "test4pass"}
+	reqBody, _ := json.Marshal(signupInput)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/auth/signup", bytes.NewBuffer(reqBody))
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// 確認前のユーザーは出品できないが、閲覧はできる
+	createItemInput := dto.CreateItemInput{Name: "テストアイテム4", Price: 4000}
+	w = requestAs(t, router, "POST", "/items", 4, createItemInput)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, `{"error":"Email not verified"}`, w.Body.String())
+	w = requestAs(t, router, "GET", "/me/items", 4, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// 不正なトークンでは確認できない
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/auth/verify?token=invalid", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 再送は登録の有無にかかわらず受け付ける
+	for _, email := range []string{"test4@example.com", "unknown@example.com"} {
+		reqBody, _ = json.Marshal(dto.ResendVerificationInput{Email: email})
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("POST", "/auth/verify/resend", bytes.NewBuffer(reqBody))
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusAccepted, w.Code)
+	}
+}
+
+func TestPasswordChange(t *testing.T) {
+	// テストのセットアップ
+	router := setup()
+
+	request := func(method string, path string, token string, body any) *httptest.ResponseRecorder {
+		reqBody, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(reqBody))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+	login := func(password string) *httptest.ResponseRecorder {
+		return request("POST", "/auth/login", "", dto.LoginInput{Email: "test4@example.com", Password: password})
+	}
+
+	w := request("POST", "/auth/signup", "", dto.SignupInput{Email: "test4@example.com", Password: "test4pass"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w = login("test4pass")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var tokens dto.TokenOutput
+	json.Unmarshal(w.Body.Bytes(), &tokens)
+
+	// 現在のパスワードが違うと変更できない
+	w = request("PUT", "/me/password", tokens.Token, dto.ChangePasswordInput{CurrentPassword: "wrongpass", NewPassword: "changed4pass"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 変更するとそれまでのトークンは使えなくなり、新しいパスワードでログインできる
+	w = request("PUT", "/me/password", tokens.Token, dto.ChangePasswordInput{CurrentPassword: "test4pass", NewPassword: "changed4pass"})
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = request("GET", "/me/items", tokens.Token, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = request("POST", "/auth/refresh", "", dto.RefreshInput{RefreshToken: tokens.RefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = login("changed4pass")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// 再設定メールは登録の有無にかかわらず受け付け、不正なトークンでは再設定できない
+	w = request("POST", "/auth/password/forgot", "", dto.ForgotPasswordInput{Email: "test4@example.com"})
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	w = request("POST", "/auth/password/forgot", "", dto.ForgotPasswordInput{Email: "unknown@example.com"})
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	w = request("POST", "/auth/password/reset", "", dto.ResetPasswordInput{Token: "invalid", Password: "reset4pass"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestJWKS(t *testing.T) {
+	// テストのセットアップ
+	router := setup()
+
+	// HS256 の場合は共有鍵を公開しない
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/.well-known/jwks.json", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"keys":[]}`, w.Body.String())
+}
+
+func TestOffers(t *testing.T) {
+	// テストのセットアップ
+	router := setup()
+
+	var res map[string]models.Offer
+
+	// 自分のアイテムや定価以上の価格では提案できない
+	w := requestAs(t, router, "POST", "/items/3/offers", 1, dto.CreateOfferInput{Price: 2000})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = requestAs(t, router, "POST", "/items/3/offers", 2, dto.CreateOfferInput{Price: 3000})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = requestAs(t, router, "POST", "/items/3/offers", 2, dto.CreateOfferInput{Price: 2000})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	json.Unmarshal([]byte(w.Body.String()), &res)
+	offerId := res["data"].ID
+
+	// 交渉中は新しく提案できず、自分の提案には返答できない
+	w = requestAs(t, router, "POST", "/items/3/offers", 2, dto.CreateOfferInput{Price: 2100})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = requestAs(t, router, "POST", fmt.Sprintf("/offers/%d/accept", offerId), 2, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// 交渉の当事者以外には見えない
+	var listRes map[string][]models.Offer
+	w = requestAs(t, router, "GET", "/items/3/offers", 3, nil)
+	json.Unmarshal([]byte(w.Body.String()), &listRes)
+	assert.Equal(t, 0, len(listRes["data"]))
+	w = requestAs(t, router, "GET", "/items/3/offers", 1, nil)
+	json.Unmarshal([]byte(w.Body.String()), &listRes)
+	assert.Equal(t, 1, len(listRes["data"]))
+
+	// 出品価格以上の価格は提案し返せない
+	w = requestAs(t, router, "POST", fmt.Sprintf("/offers/%d/counter", offerId), 1, dto.CreateOfferInput{Price: 3000})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 出品者が価格を提案し返す
+	w = requestAs(t, router, "POST", fmt.Sprintf("/offers/%d/counter", offerId), 1, dto.CreateOfferInput{Price: 2500})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	json.Unmarshal([]byte(w.Body.String()), &res)
+	counterOfferId := res["data"].ID
+	assert.Equal(t, offerId, *res["data"].ParentID)
+
+	w = requestAs(t, router, "POST", fmt.Sprintf("/offers/%d/accept", offerId), 1, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = requestAs(t, router, "POST", "/items/3/offers", 3, dto.CreateOfferInput{Price: 2200})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	json.Unmarshal([]byte(w.Body.String()), &res)
+	otherOfferId := res["data"].ID
+
+	// 承諾するとアイテムが取り置かれ、他の提案は承諾できなくなる
+	w = requestAs(t, router, "POST", fmt.Sprintf("/offers/%d/accept", counterOfferId), 2, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, models.ItemStatusReserved, findItemStatus(router, 3))
+
+	w = requestAs(t, router, "POST", fmt.Sprintf("/offers/%d/accept", otherOfferId), 1, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// 交渉が成立した購入者だけが合意した価格で購入できる
+	w = requestAs(t, router, "POST", "/items/3/purchase", 3, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = requestAs(t, router, "POST", "/items/3/purchase", 2, nil)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var orderRes map[string]models.Order
+	json.Unmarshal([]byte(w.Body.String()), &orderRes)
+	assert.Equal(t, uint(2500), orderRes["data"].Price)
+	assert.Equal(t, counterOfferId, *orderRes["data"].OfferID)
+}
+
+func TestOfferExpiration(t *testing.T) {
+	// 提案した時点で期限切れになるようにする
+	t.Setenv("OFFER_TTL", "-1s")
+	router := setup()
+
+	w := requestAs(t, router, "POST", "/items/1/offers", 2, dto.CreateOfferInput{Price: 800})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var res map[string]models.Offer
+	json.Unmarshal([]byte(w.Body.String()), &res)
+
+	w = requestAs(t, router, "POST", fmt.Sprintf("/offers/%d/accept", res["data"].ID), 1, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, models.ItemStatusListed, findItemStatus(router, 1))
+
+	var listRes map[string][]models.Offer
+	w = requestAs(t, router, "GET", "/items/1/offers", 2, nil)
+	json.Unmarshal([]byte(w.Body.String()), &listRes)
+	assert.Equal(t, models.OfferStatusExpired, listRes["data"][0].Status)
+
+	// 期限切れの後は新しく提案できる
+	w = requestAs(t, router, "POST", "/items/1/offers", 2, dto.CreateOfferInput{Price: 900})
+	assert.Equal(t, http.StatusCreated, w.Code)
+}
+
+func TestAuction(t *testing.T) {
+	// テストのセットアップ
+	router := setup()
+
+	endsAt := time.Now().Add(2 * time.Hour).Truncate(time.Second)
+	input := dto.CreateItemInput{
+		Name:        "オークションアイテム",
+		Price:       1000,
+		ListingType: models.ListingTypeAuction,
+		Auction:     &dto.CreateAuctionInput{ReservePrice: 1500, EndsAt: endsAt},
+	}
+
+	// 終了時刻がない、または近すぎるオークションは出品できない
+	w := requestAs(t, router, "POST", "/items", 1, dto.CreateItemInput{Name: "オークション", Price: 1000, ListingType: models.ListingTypeAuction})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = requestAs(t, router, "POST", "/items", 1, dto.CreateItemInput{
+		Name:        "オークション",
+		Price:       1000,
+		ListingType: models.ListingTypeAuction,
+		Auction:     &dto.CreateAuctionInput{EndsAt: time.Now().Add(time.Minute)},
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = requestAs(t, router, "POST", "/items", 1, input)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var itemRes map[string]models.Item
+	json.Unmarshal([]byte(w.Body.String()), &itemRes)
+	itemId := itemRes["data"].ID
+	assert.Equal(t, uint(1000), itemRes["data"].Auction.StartPrice)
+	path := fmt.Sprintf("/items/%d", itemId)
+
+	// 出品者は入札できず、開始価格未満の入札は受け付けない
+	w = requestAs(t, router, "POST", path+"/bids", 1, dto.PlaceBidInput{Amount: 1000})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = requestAs(t, router, "POST", path+"/bids", 2, dto.PlaceBidInput{Amount: 900})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// オークションは即決購入や値下げ交渉、価格の変更ができない
+	w = requestAs(t, router, "POST", path+"/purchase", 2, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = requestAs(t, router, "POST", path+"/offers", 2, dto.CreateOfferInput{Price: 500})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	price := uint(500)
+	reqBody, _ := json.Marshal(dto.UpdateItemInput{Price: &price})
+	token := loginAs(t, router, 1)
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", path, bytes.NewBuffer(reqBody))
+	req.Header.Set("If-Match", `"1"`)
+	req.Header.Set("Authorization", "Bearer "+*token)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// 同じ金額で同時に入札しても受け付けるのは1件だけ
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		codes = map[int]int{}
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w := requestAs(t, router, "POST", path+"/bids", uint(2+i%2), dto.PlaceBidInput{Amount: 1200})
+			mu.Lock()
+			codes[w.Code]++
+			mu.Unlock()
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, 1, codes[http.StatusCreated])
+	assert.Equal(t, 9, codes[http.StatusConflict])
+
+	w = requestAs(t, router, "POST", path+"/bids", 2, dto.PlaceBidInput{Amount: 1500})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var bidRes map[string]dto.BidOutput
+	json.Unmarshal([]byte(w.Body.String()), &bidRes)
+	assert.Equal(t, uint(1500), bidRes["data"].Amount)
+	assert.Equal(t, uint(1500), bidRes["data"].Auction.CurrentPrice)
+	assert.Equal(t, 2, bidRes["data"].Auction.BidCount)
+
+	// 入札履歴は誰でも見られる
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", path+"/bids", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var bidListRes struct {
+		Data  []models.Bid `json:"data"`
+		Total int64        `json:"total"`
+	}
+	json.Unmarshal([]byte(w.Body.String()), &bidListRes)
+	assert.Equal(t, int64(2), bidListRes.Total)
+	assert.Equal(t, uint(1500), bidListRes.Data[0].Amount)
+
+	// 入札のあるオークションは取り下げられない
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", path, nil)
+	req.Header.Set("If-Match", `"1"`)
+	req.Header.Set("Authorization", "Bearer "+*token)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
 }
@@ -0,0 +1,26 @@
+package middlewares
+
+import (
+	"gin-fleamarket/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminMiddleware は AuthMiddleware の後に適用し、管理者以外のアクセスを拒否します
+func AdminMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user, exists := ctx.Get("user")
+		if !exists {
+			ctx.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		if !user.(*models.User).IsAdmin {
+			ctx.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+
+		ctx.Next()
+	}
+}
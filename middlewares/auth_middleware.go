@@ -29,6 +29,7 @@ func AuthMiddleware(authService services.IAuthService) gin.HandlerFunc {
 		}
 
 		ctx.Set("user", user)
+		ctx.Set("token", tokenString)
 
 		ctx.Next()
 	}
@@ -0,0 +1,26 @@
+package middlewares
+
+import (
+	"gin-fleamarket/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VerifiedMiddleware は AuthMiddleware の後に適用し、メールアドレスの確認が済んでいないユーザーのアクセスを拒否します
+func VerifiedMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user, exists := ctx.Get("user")
+		if !exists {
+			ctx.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		if user.(*models.User).EmailVerifiedAt == nil {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Email not verified"})
+			return
+		}
+
+		ctx.Next()
+	}
+}
@@ -3,13 +3,73 @@ package main
 import (
 	"gin-fleamarket/infra"
 	"gin-fleamarket/models"
+	"gin-fleamarket/repositories"
+	"gin-fleamarket/services"
+
+	"gorm.io/gorm"
 )
 
 func main() {
 	infra.Initialize()
 	db := infra.SetupDB()
 
-	if err := db.AutoMigrate(&models.Item{}, &models.User{}); err != nil {
+	// メールアドレスの確認を導入する前に登録したユーザーは確認済みとして扱う
+	verifyExistingUsers := !db.Migrator().HasColumn(&models.User{}, "EmailVerifiedAt")
+
+	if err := db.AutoMigrate(&models.Item{}, &models.User{}, &models.ItemSearchToken{}, &models.Category{}, &models.ItemImage{}, &models.Order{}, &models.PaymentEvent{}, &models.OrderEvent{}, &models.OrderRating{}, &models.LedgerEntry{}, &models.Payout{}, &models.Offer{}, &models.Auction{}, &models.Bid{}, &models.OrderMessage{}, &models.ItemComment{}, &models.Notification{}, &models.ItemLike{}, &models.RefreshToken{}, &models.RevokedToken{}, &models.UserToken{}, &models.OutboxMail{}); err != nil {
 		panic("Failed to migrate database")
 	}
+
+	if verifyExistingUsers {
+		if err := db.Exec("UPDATE users SET email_verified_at = created_at WHERE email_verified_at IS NULL").Error; err != nil {
+			panic("Failed to migrate email_verified_at column")
+		}
+	}
+
+	// SoldOut カラムを Status に移行する
+	if db.Migrator().HasColumn(&models.Item{}, "sold_out") {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec("UPDATE items SET status = ? WHERE sold_out = ?", models.ItemStatusSold, true).Error; err != nil {
+				return err
+			}
+			if err := tx.Exec("UPDATE items SET status = ? WHERE sold_out = ?", models.ItemStatusListed, false).Error; err != nil {
+				return err
+			}
+			return tx.Migrator().DropColumn(&models.Item{}, "sold_out")
+		})
+		if err != nil {
+			panic("Failed to migrate sold_out column")
+		}
+	}
+
+	// 受け取った評価の件数を評価から集計し直す
+	for rating, column := range models.RatingCountColumns {
+		err := db.Exec(
+			"UPDATE users SET "+column+" = (SELECT COUNT(*) FROM order_ratings WHERE order_ratings.ratee_id = users.id AND order_ratings.rating = ? AND order_ratings.deleted_at IS NULL)",
+			rating,
+		).Error
+		if err != nil {
+			panic("Failed to migrate rating counts")
+		}
+	}
+
+	// 既存アイテムの検索インデックスを作り直す。公開中でないアイテムのインデックスは削除する
+	publicStatuses := services.PublicItemStatuses()
+	publicItemIds := db.Model(&models.Item{}).Select("id").Where("status IN ?", publicStatuses)
+	if err := db.Where("item_id NOT IN (?)", publicItemIds).Delete(&models.ItemSearchToken{}).Error; err != nil {
+		panic("Failed to clean up search index")
+	}
+	searchService := services.NewSearchService(repositories.NewSearchRepository(db))
+	var items []models.Item
+	result := db.Where("status IN ?", publicStatuses).FindInBatches(&items, 100, func(tx *gorm.DB, batch int) error {
+		for _, item := range items {
+			if err := searchService.IndexItem(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if result.Error != nil {
+		panic("Failed to build search index")
+	}
 }
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	AuctionStatusOpen   = "open"
+	AuctionStatusClosed = "closed"
+)
+
+// Auction はオークション形式の出品の状態です。開始価格はアイテムの Price と同じです
+// ReservePrice を下回る最高入札額では落札されません
+type Auction struct {
+	gorm.Model
+	ItemID          uint `gorm:"not null;uniqueIndex"`
+	StartPrice      uint `gorm:"not null"`
+	ReservePrice    uint `gorm:"not null;default:0"`
+	CurrentPrice    uint `gorm:"not null;default:0"`
+	BidCount        int  `gorm:"not null;default:0"`
+	HighestBidderID *uint
+	EndsAt          time.Time `gorm:"not null;index"`
+	Status          string    `gorm:"not null;default:open;index"`
+	WinnerID        *uint
+	OrderID         *uint
+	ClosedAt        *time.Time
+}
+
+type Bid struct {
+	gorm.Model
+	AuctionID uint `gorm:"not null;index"`
+	BidderID  uint `gorm:"not null;index"`
+	Amount    uint `gorm:"not null"`
+}
@@ -0,0 +1,10 @@
+package models
+
+import "gorm.io/gorm"
+
+type Category struct {
+	gorm.Model
+	Name     string     `gorm:"not null"`
+	ParentID *uint      `gorm:"index"`
+	Children []Category `gorm:"foreignKey:ParentID"`
+}
@@ -2,11 +2,33 @@ package models
 
 import "gorm.io/gorm"
 
+const (
+	ItemStatusDraft     = "draft"
+	ItemStatusListed    = "listed"
+	ItemStatusReserved  = "reserved"
+	ItemStatusSold      = "sold"
+	ItemStatusShipped   = "shipped"
+	ItemStatusCompleted = "completed"
+	ItemStatusCancelled = "cancelled"
+
+	ListingTypeFixed   = "fixed"
+	ListingTypeAuction = "auction"
+)
+
 type Item struct {
 	gorm.Model
 	Name        string `gorm:"not null"`
 	Price       uint   `gorm:"not null"`
 	Description string
-	SoldOut     bool `gorm:"not null;default:false"`
-	UserID      uint `gorm:"not null"`
+	Status      string `gorm:"not null;default:listed;index"`
+	UserID      uint   `gorm:"not null"`
+	CategoryID  *uint  `gorm:"index"`
+	ListingType string `gorm:"not null;default:fixed"`
+	// LikeCount はいいねの件数です。いいねの追加・取り消しと同じトランザクションで増減します
+	LikeCount uint `gorm:"not null;default:0"`
+	// Version は更新のたびに1ずつ増え、楽観的排他制御に使います
+	Version uint `gorm:"not null;default:1"`
+	Images  []ItemImage
+	// Auction はオークション形式の出品の場合のみ設定されます
+	Auction *Auction
 }
@@ -0,0 +1,12 @@
+package models
+
+import "gorm.io/gorm"
+
+// ItemComment は出品への質問と、それに対する出品者の回答です。回答は ParentID に質問のIDを持ちます
+type ItemComment struct {
+	gorm.Model
+	ItemID   uint   `gorm:"not null;index"`
+	UserID   uint   `gorm:"not null"`
+	ParentID *uint  `gorm:"index"`
+	Body     string `gorm:"not null"`
+}
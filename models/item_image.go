@@ -0,0 +1,16 @@
+package models
+
+import "gorm.io/gorm"
+
+type ItemImage struct {
+	gorm.Model
+	ItemID       uint   `gorm:"not null;index"`
+	Position     int    `gorm:"not null"`
+	Key          string `gorm:"not null"`
+	ThumbnailKey string `gorm:"not null"`
+	URL          string `gorm:"not null"`
+	ThumbnailURL string `gorm:"not null"`
+	ContentType  string `gorm:"not null"`
+	Width        int    `gorm:"not null"`
+	Height       int    `gorm:"not null"`
+}
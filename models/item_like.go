@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// ItemLike はユーザーがアイテムにつけたいいねです。ユーザーはアイテムごとに1回だけいいねできます
+type ItemLike struct {
+	ID        uint `gorm:"primarykey"`
+	UserID    uint `gorm:"not null;uniqueIndex:idx_item_likes_user_item"`
+	ItemID    uint `gorm:"not null;uniqueIndex:idx_item_likes_user_item;index"`
+	CreatedAt time.Time
+}
@@ -0,0 +1,9 @@
+package models
+
+// ItemSearchToken はアイテムの名前と説明から作ったn-gramの転置インデックスです
+type ItemSearchToken struct {
+	ID     uint   `gorm:"primaryKey"`
+	ItemID uint   `gorm:"not null;index"`
+	Token  string `gorm:"not null;index"`
+	Weight uint   `gorm:"not null"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+const (
+	LedgerAccountUserAvailable  = "user_available"
+	LedgerAccountUserLocked     = "user_locked"
+	LedgerAccountPlatformEscrow = "platform_escrow"
+	LedgerAccountPlatformFees   = "platform_fees"
+	LedgerAccountExternal       = "external"
+
+	LedgerEntryTypePayment       = "payment"
+	LedgerEntryTypeSale          = "sale"
+	LedgerEntryTypeFee           = "fee"
+	LedgerEntryTypeRefund        = "refund"
+	LedgerEntryTypePayoutRequest = "payout_request"
+	LedgerEntryTypePayout        = "payout"
+)
+
+// LedgerEntry は複式簿記の仕訳の1行です。追記のみで、更新や削除はしません
+// 同じ TransactionID を持つ行の Amount の合計は必ず0になり、勘定の残高はその勘定の Amount の合計です
+// UserID はユーザーごとの勘定の場合のみ設定されます
+type LedgerEntry struct {
+	ID            uint   `gorm:"primarykey"`
+	TransactionID string `gorm:"not null;index"`
+	Account       string `gorm:"not null;index:idx_ledger_entries_account"`
+	UserID        *uint  `gorm:"index:idx_ledger_entries_account"`
+	Amount        int64  `gorm:"not null"`
+	Type          string `gorm:"not null"`
+	OrderID       *uint  `gorm:"index"`
+	PayoutID      *uint  `gorm:"index"`
+	CreatedAt     time.Time
+}
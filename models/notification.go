@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	NotificationTypeItemQuestion = "item_question"
+)
+
+// Notification はユーザーへのお知らせです。ReadAt はユーザーが既読にした日時です
+type Notification struct {
+	gorm.Model
+	UserID    uint   `gorm:"not null;index"`
+	Type      string `gorm:"not null"`
+	ItemID    *uint
+	CommentID *uint
+	Message   string `gorm:"not null"`
+	ReadAt    *time.Time
+}
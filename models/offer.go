@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	OfferStatusPending   = "pending"
+	OfferStatusAccepted  = "accepted"
+	OfferStatusRejected  = "rejected"
+	OfferStatusCountered = "countered"
+	OfferStatusExpired   = "expired"
+	OfferStatusPurchased = "purchased"
+)
+
+// Offer は値下げ交渉の提案です。ProposedBy ではない側が承諾・拒否・再提案できます
+// 再提案すると元の提案は countered になり、ParentID に元の提案を持つ新しい提案が作られます
+// ExpiresAt は pending の間は返答の期限、accepted になった後は購入の期限です
+type Offer struct {
+	gorm.Model
+	ItemID     uint   `gorm:"not null;index"`
+	BuyerID    uint   `gorm:"not null;index"`
+	SellerID   uint   `gorm:"not null;index"`
+	ProposedBy uint   `gorm:"not null"`
+	ParentID   *uint  `gorm:"index"`
+	Price      uint   `gorm:"not null"`
+	Status     string `gorm:"not null;index"`
+	ExpiresAt  time.Time
+}
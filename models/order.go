@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	OrderStatusPendingPayment = "pending_payment"
+	OrderStatusPaid           = "paid"
+	OrderStatusFailed         = "failed"
+	OrderStatusShipped        = "shipped"
+	OrderStatusReceived       = "received"
+	OrderStatusCapturing      = "capturing"
+	OrderStatusCompleted      = "completed"
+	OrderStatusRefunding      = "refunding"
+	OrderStatusCancelled      = "cancelled"
+)
+
+type Order struct {
+	gorm.Model
+	ItemID         uint `gorm:"not null;index"`
+	BuyerID        uint `gorm:"not null;index"`
+	SellerID       uint `gorm:"not null;index"`
+	Price          uint `gorm:"not null"`
+	OfferID        *uint
+	Status         string `gorm:"not null;index"`
+	PaymentID      string `gorm:"index"`
+	TrackingNumber string
+	ShippedAt      *time.Time
+	ReceivedAt     *time.Time
+	CompletedAt    *time.Time
+	CancelledAt    *time.Time
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+const (
+	OrderActionPurchase          = "purchase"
+	OrderActionAuctionWon        = "auction_won"
+	OrderActionPaymentAuthorized = "payment_authorized"
+	OrderActionPaymentFailed     = "payment_failed"
+	OrderActionShip              = "ship"
+	OrderActionReceive           = "receive"
+	OrderActionRate              = "rate"
+	OrderActionComplete          = "complete"
+	OrderActionCancel            = "cancel"
+	OrderActionRefund            = "refund"
+)
+
+// OrderEvent は注文に対して誰がいつ何をしたかの履歴です
+// ActorID が nil の場合は決済の通知などシステムによる操作です
+type OrderEvent struct {
+	ID         uint `gorm:"primarykey"`
+	OrderID    uint `gorm:"not null;index"`
+	ActorID    *uint
+	Action     string `gorm:"not null"`
+	FromStatus string
+	ToStatus   string `gorm:"not null"`
+	Note       string
+	CreatedAt  time.Time
+}
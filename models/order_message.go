@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// OrderMessage は取引の当事者同士でやり取りするメッセージです。ReadAt は受信者が既読にした日時です
+type OrderMessage struct {
+	gorm.Model
+	OrderID     uint       `gorm:"not null;index"`
+	SenderID    uint       `gorm:"not null"`
+	RecipientID uint       `gorm:"not null;index:idx_order_messages_recipient_read"`
+	Body        string     `gorm:"not null"`
+	ReadAt      *time.Time `gorm:"index:idx_order_messages_recipient_read"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "gorm.io/gorm"
+
+const (
+	RatingGood   = "good"
+	RatingNormal = "normal"
+	RatingBad    = "bad"
+)
+
+// RatingCountColumns は評価ごとに件数を保持する users のカラムです
+var RatingCountColumns = map[string]string{
+	RatingGood:   "good_rating_count",
+	RatingNormal: "normal_rating_count",
+	RatingBad:    "bad_rating_count",
+}
+
+// OrderRating は取引の相手に対する評価です。1つの注文につき購入者と出品者がそれぞれ1回ずつ評価します
+type OrderRating struct {
+	gorm.Model
+	OrderID uint   `gorm:"not null;uniqueIndex:idx_order_ratings_order_rater"`
+	RaterID uint   `gorm:"not null;uniqueIndex:idx_order_ratings_order_rater"`
+	RateeID uint   `gorm:"not null;index"`
+	Rating  string `gorm:"not null"`
+	Comment string
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// OutboxMail は送信する代わりにデータベースへ保存したメールです。開発環境やテストで送信内容を確認するのに使います
+type OutboxMail struct {
+	ID        uint   `gorm:"primarykey"`
+	To        string `gorm:"not null;index"`
+	Subject   string `gorm:"not null"`
+	Body      string `gorm:"not null"`
+	CreatedAt time.Time
+}
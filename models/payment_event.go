@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// PaymentEvent は処理済みの決済 Webhook イベントです。同じイベントを二重に処理しないために使います
+type PaymentEvent struct {
+	ID        uint   `gorm:"primarykey"`
+	EventID   string `gorm:"not null;uniqueIndex"`
+	Type      string `gorm:"not null"`
+	PaymentID string `gorm:"not null"`
+	CreatedAt time.Time
+}
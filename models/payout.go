@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	PayoutStatusRequested = "requested"
+	PayoutStatusPaid      = "paid"
+)
+
+type Payout struct {
+	gorm.Model
+	UserID uint   `gorm:"not null;index"`
+	Amount uint   `gorm:"not null"`
+	Status string `gorm:"not null;index"`
+	PaidAt *time.Time
+}
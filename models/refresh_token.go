@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// RefreshToken はアクセストークンを再発行するためのトークンです。トークン自体は保存せず、SHA-256 のハッシュだけを保存します
+// 再発行のたびに同じ FamilyID で新しいトークンに置き換わり、使用済みのトークンには UsedAt が記録されます
+type RefreshToken struct {
+	ID        uint      `gorm:"primarykey"`
+	UserID    uint      `gorm:"not null;index"`
+	FamilyID  string    `gorm:"not null;index"`
+	TokenHash string    `gorm:"not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
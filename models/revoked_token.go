@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// RevokedToken はログアウトで失効させたアクセストークンの jti です
+// ExpiresAt を過ぎたトークンはどのみち検証に失敗するので、それ以降は削除してかまいません
+type RevokedToken struct {
+	JTI       string    `gorm:"primarykey"`
+	UserID    uint      `gorm:"not null"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+	CreatedAt time.Time
+}
@@ -1,10 +1,23 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type User struct {
 	gorm.Model
 	Email    string `gorm:"not null:unique"`
 	Password string `gorm:"not null"`
-	items    []Item `gorm:"constraint:OnDelete:CASCADE"`
+	IsAdmin  bool   `gorm:"not null;default:false"`
+	// EmailVerifiedAt はメールアドレスの確認が済んだ日時です。確認が済むまでは出品できません
+	EmailVerifiedAt *time.Time
+	// 取引相手から受け取った評価の件数です。評価の作成と同じトランザクションで更新します
+	GoodRatingCount   int64 `gorm:"not null;default:0"`
+	NormalRatingCount int64 `gorm:"not null;default:0"`
+	BadRatingCount    int64 `gorm:"not null;default:0"`
+	// TokenVersion はアクセストークンの ver クレームと一致する必要があり、全端末からログアウトすると1つ増えます
+	TokenVersion uint   `gorm:"not null;default:0"`
+	items        []Item `gorm:"constraint:OnDelete:CASCADE"`
 }
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+const (
+	UserTokenPurposeEmailVerification = "email_verification"
+	UserTokenPurposePasswordReset     = "password_reset"
+)
+
+// UserToken はメールで送る一度だけ使えるトークンです。トークン自体は保存せず、SHA-256 のハッシュだけを保存します
+type UserToken struct {
+	ID        uint      `gorm:"primarykey"`
+	UserID    uint      `gorm:"not null;index"`
+	Purpose   string    `gorm:"not null"`
+	TokenHash string    `gorm:"not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
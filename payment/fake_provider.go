@@ -0,0 +1,126 @@
+package payment
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// FakeProvider は外部と通信せずにメモリ上で決済を扱う開発・テスト用のプロバイダーです
+// webhookURL が設定されている場合は、与信のたびに署名付きのイベントをそのURLへ送ります
+type FakeProvider struct {
+	mu         sync.Mutex
+	payments   map[string]*Payment
+	references map[string]string
+	secret     string
+	webhookURL string
+	client     *http.Client
+}
+
+func NewFakeProvider(secret string, webhookURL string) IProvider {
+	return &FakeProvider{
+		payments:   map[string]*Payment{},
+		references: map[string]string{},
+		secret:     secret,
+		webhookURL: webhookURL,
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (p *FakeProvider) Authorize(reference string, amount uint) (*Payment, error) {
+	if amount == 0 {
+		return nil, errors.New("Invalid amount")
+	}
+	id, err := randomId("pay_")
+	if err != nil {
+		return nil, err
+	}
+
+	// 同じ reference の与信が既にあればそれを返し、イベントを送り直す
+	p.mu.Lock()
+	payment, ok := p.payments[p.references[reference]]
+	if !ok {
+		payment = &Payment{ID: id, Reference: reference, Amount: amount, Status: PaymentStatusAuthorized}
+		p.payments[id] = payment
+		p.references[reference] = id
+	}
+	result := *payment
+	p.mu.Unlock()
+
+	if p.webhookURL != "" {
+		go p.deliver(EventPaymentAuthorized, result)
+	}
+	return &result, nil
+}
+
+func (p *FakeProvider) Capture(paymentId string) (*Payment, error) {
+	return p.transition(paymentId, PaymentStatusCaptured, PaymentStatusAuthorized)
+}
+
+// Refund は売上確定前であれば与信を取り消し、確定後であれば返金します
+func (p *FakeProvider) Refund(paymentId string) (*Payment, error) {
+	return p.transition(paymentId, PaymentStatusRefunded, PaymentStatusAuthorized, PaymentStatusCaptured)
+}
+
+func (p *FakeProvider) transition(paymentId string, status string, from ...string) (*Payment, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	payment, ok := p.payments[paymentId]
+	if !ok {
+		return nil, errors.New("Payment not found")
+	}
+	if payment.Status == status {
+		result := *payment
+		return &result, nil
+	}
+	for _, s := range from {
+		if payment.Status == s {
+			payment.Status = status
+			result := *payment
+			return &result, nil
+		}
+	}
+	return nil, errors.New("Invalid payment status")
+}
+
+func (p *FakeProvider) deliver(eventType string, payment Payment) {
+	id, err := randomId("evt_")
+	if err != nil {
+		log.Printf("Failed to deliver payment event: %v", err)
+		return
+	}
+	body, err := json.Marshal(Event{ID: id, Type: eventType, PaymentID: payment.ID, Reference: payment.Reference})
+	if err != nil {
+		log.Printf("Failed to deliver payment event: %v", err)
+		return
+	}
+
+	req, err := http.NewRequest("POST", p.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		log.Printf("Failed to deliver payment event: %v", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(p.secret, body))
+	res, err := p.client.Do(req)
+	if err != nil {
+		log.Printf("Failed to deliver payment event: %v", err)
+		return
+	}
+	res.Body.Close()
+}
+
+func randomId(prefix string) (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return prefix + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,76 @@
+package payment
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestFakeProvider(t *testing.T) {
+	received := make(chan Event, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !VerifySignature("secret", body, r.Header.Get(SignatureHeader)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var event Event
+		json.Unmarshal(body, &event)
+		received <- event
+	}))
+	defer server.Close()
+
+	provider := NewFakeProvider("secret", server.URL)
+
+	// テストケース1: 与信すると署名付きのイベントが届く
+	p, err := provider.Authorize("1", 1000)
+	assert.NilError(t, err)
+	assert.Equal(t, PaymentStatusAuthorized, p.Status)
+
+	select {
+	case event := <-received:
+		assert.Equal(t, EventPaymentAuthorized, event.Type)
+		assert.Equal(t, p.ID, event.PaymentID)
+		assert.Equal(t, "1", event.Reference)
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+
+	// テストケース2: 同じ reference の与信は同じ支払いになる
+	again, err := provider.Authorize("1", 1000)
+	assert.NilError(t, err)
+	assert.Equal(t, p.ID, again.ID)
+
+	// テストケース3: 売上確定後の返金。同じ操作を繰り返しても成功する
+	p, err = provider.Capture(p.ID)
+	assert.NilError(t, err)
+	assert.Equal(t, PaymentStatusCaptured, p.Status)
+	p, err = provider.Capture(p.ID)
+	assert.NilError(t, err)
+	assert.Equal(t, PaymentStatusCaptured, p.Status)
+
+	p, err = provider.Refund(p.ID)
+	assert.NilError(t, err)
+	assert.Equal(t, PaymentStatusRefunded, p.Status)
+	_, err = provider.Refund(p.ID)
+	assert.NilError(t, err)
+	_, err = provider.Capture(p.ID)
+	assert.Error(t, err, "Invalid payment status")
+
+	// テストケース4: 存在しない支払い
+	_, err = provider.Refund("pay_unknown")
+	assert.Error(t, err, "Payment not found")
+}
+
+func TestVerifySignature(t *testing.T) {
+	body := []byte(`{"id":"evt_1"}`)
+	assert.Assert(t, VerifySignature("secret", body, Sign("secret", body)))
+	assert.Assert(t, !VerifySignature("other", body, Sign("secret", body)))
+	assert.Assert(t, !VerifySignature("", body, Sign("", body)))
+	assert.Assert(t, !VerifySignature("secret", body, "not-hex"))
+}
@@ -0,0 +1,33 @@
+package payment
+
+import "os"
+
+const (
+	PaymentStatusAuthorized = "authorized"
+	PaymentStatusCaptured   = "captured"
+	PaymentStatusRefunded   = "refunded"
+	PaymentStatusFailed     = "failed"
+)
+
+type Payment struct {
+	ID        string
+	Reference string
+	Amount    uint
+	Status    string
+}
+
+// IProvider は決済代行サービスとのやり取りを抽象化します
+// 与信の結果は Authorize の戻り値ではなく Webhook のイベントで通知されます
+// どの操作も冪等で、Authorize は同じ reference に対して同じ支払いを返し、Capture と Refund は既にその状態になっている支払いに対しては何もせずに成功します
+type IProvider interface {
+	Authorize(reference string, amount uint) (*Payment, error)
+	Capture(paymentId string) (*Payment, error)
+	Refund(paymentId string) (*Payment, error)
+}
+
+// SetupProvider は環境変数から決済プロバイダーと Webhook の署名鍵を作ります
+// 現在はローカルで完結するフェイクのプロバイダーのみ対応しています
+func SetupProvider() (IProvider, string) {
+	secret := os.Getenv("PAYMENT_WEBHOOK_SECRET")
+	return NewFakeProvider(secret, os.Getenv("PAYMENT_WEBHOOK_URL")), secret
+}
@@ -0,0 +1,44 @@
+package payment
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+const (
+	EventPaymentAuthorized = "payment.authorized"
+	EventPaymentFailed     = "payment.failed"
+
+	SignatureHeader = "X-Payment-Signature"
+)
+
+type Event struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	PaymentID string `json:"paymentId"`
+	Reference string `json:"reference"`
+}
+
+// Sign は Webhook の本文に対する HMAC-SHA256 の署名を16進数で返します
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature は署名が本文と一致するかを確認します。署名鍵が未設定の場合は常に失敗します
+func VerifySignature(secret string, body []byte, signature string) bool {
+	if secret == "" {
+		return false
+	}
+	expected, err := hex.DecodeString(Sign(secret, body))
+	if err != nil {
+		return false
+	}
+	actual, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(expected, actual)
+}
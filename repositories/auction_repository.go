@@ -0,0 +1,108 @@
+package repositories
+
+import (
+	"errors"
+	"gin-fleamarket/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IAuctionRepository interface {
+	FindByItem(itemId uint) (*models.Auction, error)
+	LockByItem(itemId uint) (*models.Auction, error)
+	// Update は読み込んだ時点から入札数が変わっていない場合のみ更新します
+	Update(updateAuction models.Auction, expectedBidCount int) (*models.Auction, error)
+	FindEndedItemIds(now time.Time) ([]uint, error)
+	CreateBid(newBid models.Bid) (*models.Bid, error)
+	FindBids(auctionId uint, limit int, offset int) (*[]models.Bid, int64, error)
+}
+
+type AuctionRepository struct {
+	db *gorm.DB
+}
+
+func NewAuctionRepository(db *gorm.DB) IAuctionRepository {
+	return &AuctionRepository{db: db}
+}
+
+// FindByItem implements IAuctionRepository.
+func (r *AuctionRepository) FindByItem(itemId uint) (*models.Auction, error) {
+	var auction models.Auction
+	result := r.db.First(&auction, "item_id = ?", itemId)
+	if result.Error != nil {
+		if result.Error.Error() == "record not found" {
+			return nil, errors.New("Auction not found")
+		}
+		return nil, result.Error
+	}
+	return &auction, nil
+}
+
+// LockByItem implements IAuctionRepository.
+func (r *AuctionRepository) LockByItem(itemId uint) (*models.Auction, error) {
+	var auction models.Auction
+	result := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&auction, "item_id = ?", itemId)
+	if result.Error != nil {
+		if result.Error.Error() == "record not found" {
+			return nil, errors.New("Auction not found")
+		}
+		return nil, result.Error
+	}
+	return &auction, nil
+}
+
+// Update implements IAuctionRepository.
+func (r *AuctionRepository) Update(updateAuction models.Auction, expectedBidCount int) (*models.Auction, error) {
+	result := r.db.Model(&updateAuction).
+		Where("bid_count = ?", expectedBidCount).
+		Select("*").Omit("CreatedAt", "DeletedAt").
+		Updates(&updateAuction)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("Version conflict")
+	}
+	return &updateAuction, nil
+}
+
+// FindEndedItemIds implements IAuctionRepository.
+func (r *AuctionRepository) FindEndedItemIds(now time.Time) ([]uint, error) {
+	var itemIds []uint
+	result := r.db.Model(&models.Auction{}).
+		Where("status = ? AND ends_at <= ?", models.AuctionStatusOpen, now).
+		Order("ends_at").
+		Pluck("item_id", &itemIds)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return itemIds, nil
+}
+
+// CreateBid implements IAuctionRepository.
+func (r *AuctionRepository) CreateBid(newBid models.Bid) (*models.Bid, error) {
+	result := r.db.Create(&newBid)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &newBid, nil
+}
+
+// FindBids implements IAuctionRepository.
+func (r *AuctionRepository) FindBids(auctionId uint, limit int, offset int) (*[]models.Bid, int64, error) {
+	query := r.db.Model(&models.Bid{}).Where("auction_id = ?", auctionId)
+
+	var total int64
+	if result := query.Session(&gorm.Session{}).Count(&total); result.Error != nil {
+		return nil, 0, result.Error
+	}
+
+	var bids []models.Bid
+	result := query.Order("id DESC").Limit(limit).Offset(offset).Find(&bids)
+	if result.Error != nil {
+		return nil, 0, result.Error
+	}
+	return &bids, total, nil
+}
@@ -3,13 +3,43 @@ package repositories
 import (
 	"errors"
 	"gin-fleamarket/models"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type IAuthRepository interface {
-	CreateUser(user models.User) error
+	Transaction(fn func(tx IAuthRepository) error) error
+	CreateUser(user models.User) (*models.User, error)
 	FindUser(email string) (*models.User, error)
+	FindUserById(userId uint) (*models.User, error)
+	UpdatePassword(userId uint, hashedPassword string) error
+	// VerifyEmail はユーザーのメールアドレスを確認済みにします。既に確認済みの場合は確認日時を変えません
+	VerifyEmail(userId uint, verifiedAt time.Time) error
+	CreateUserToken(newToken models.UserToken) (*models.UserToken, error)
+	FindUserToken(tokenHash string, purpose string) (*models.UserToken, error)
+	// MarkUserTokenUsed は未使用のトークンを使用済みにします。既に使用済みだった場合は false を返します
+	MarkUserTokenUsed(tokenId uint, usedAt time.Time) (bool, error)
+	// ExpireUserTokens はユーザーの未使用のトークンのうち purpose が一致するものをすべて使用済みにします
+	ExpireUserTokens(userId uint, purpose string, usedAt time.Time) error
+	// HasRecentUserToken は since 以降に発行された purpose が一致する未使用のトークンがあるかどうかを返します
+	HasRecentUserToken(userId uint, purpose string, since time.Time) (bool, error)
+	CreateRefreshToken(newToken models.RefreshToken) (*models.RefreshToken, error)
+	FindRefreshToken(tokenHash string) (*models.RefreshToken, error)
+	// MarkRefreshTokenUsed は未使用のリフレッシュトークンを使用済みにします。既に使用済みだった場合は false を返します
+	MarkRefreshTokenUsed(tokenId uint, usedAt time.Time) (bool, error)
+	// RevokeRefreshTokenFamily は同じファミリーのリフレッシュトークンをすべて失効させます
+	RevokeRefreshTokenFamily(familyId string, revokedAt time.Time) error
+	// RevokeUserRefreshTokens はユーザーのリフレッシュトークンをすべて失効させます
+	RevokeUserRefreshTokens(userId uint, revokedAt time.Time) error
+	// RevokeToken はアクセストークンの jti を失効済みとして記録します。既に記録されている場合は何もしません
+	RevokeToken(revokedToken models.RevokedToken) error
+	IsTokenRevoked(jti string) (bool, error)
+	// DeleteExpiredRevokedTokens は now までに有効期限が切れた失効済みトークンの記録を削除し、削除した件数を返します
+	DeleteExpiredRevokedTokens(now time.Time) (int64, error)
+	// IncrementTokenVersion はユーザーのトークンバージョンを1つ増やし、発行済みのアクセストークンをすべて無効にします
+	IncrementTokenVersion(userId uint) error
 }
 
 type AuthRepository struct {
@@ -20,12 +50,19 @@ func NewAuthRepository(db *gorm.DB) IAuthRepository {
 	return &AuthRepository{db: db}
 }
 
-func (r *AuthRepository) CreateUser(user models.User) error {
+// Transaction implements IAuthRepository.
+func (r *AuthRepository) Transaction(fn func(tx IAuthRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&AuthRepository{db: tx})
+	})
+}
+
+func (r *AuthRepository) CreateUser(user models.User) (*models.User, error) {
 	result := r.db.Create(&user)
 	if result.Error != nil {
-		return result.Error
+		return nil, result.Error
 	}
-	return nil
+	return &user, nil
 }
 
 func (r *AuthRepository) FindUser(email string) (*models.User, error) {
@@ -40,3 +77,154 @@ func (r *AuthRepository) FindUser(email string) (*models.User, error) {
 	return &user, nil
 
 }
+
+func (r *AuthRepository) FindUserById(userId uint) (*models.User, error) {
+	var user models.User
+	result := r.db.First(&user, "id = ?", userId)
+	if result.Error != nil {
+		if result.Error.Error() == "record not found" {
+			return nil, errors.New("User not found")
+		}
+		return nil, result.Error
+	}
+	return &user, nil
+}
+
+func (r *AuthRepository) UpdatePassword(userId uint, hashedPassword string) error {
+	result := r.db.Model(&models.User{}).Where("id = ?", userId).Update("password", hashedPassword)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("User not found")
+	}
+	return nil
+}
+
+func (r *AuthRepository) VerifyEmail(userId uint, verifiedAt time.Time) error {
+	return r.db.Model(&models.User{}).
+		Where("id = ? AND email_verified_at IS NULL", userId).
+		Update("email_verified_at", verifiedAt).Error
+}
+
+func (r *AuthRepository) CreateUserToken(newToken models.UserToken) (*models.UserToken, error) {
+	result := r.db.Create(&newToken)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &newToken, nil
+}
+
+func (r *AuthRepository) FindUserToken(tokenHash string, purpose string) (*models.UserToken, error) {
+	var token models.UserToken
+	result := r.db.First(&token, "token_hash = ? AND purpose = ?", tokenHash, purpose)
+	if result.Error != nil {
+		if result.Error.Error() == "record not found" {
+			return nil, errors.New("Invalid token")
+		}
+		return nil, result.Error
+	}
+	return &token, nil
+}
+
+func (r *AuthRepository) MarkUserTokenUsed(tokenId uint, usedAt time.Time) (bool, error) {
+	result := r.db.Model(&models.UserToken{}).
+		Where("id = ? AND used_at IS NULL", tokenId).
+		Update("used_at", usedAt)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *AuthRepository) ExpireUserTokens(userId uint, purpose string, usedAt time.Time) error {
+	return r.db.Model(&models.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userId, purpose).
+		Update("used_at", usedAt).Error
+}
+
+func (r *AuthRepository) HasRecentUserToken(userId uint, purpose string, since time.Time) (bool, error) {
+	var count int64
+	result := r.db.Model(&models.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL AND created_at >= ?", userId, purpose, since).
+		Count(&count)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return count > 0, nil
+}
+
+func (r *AuthRepository) CreateRefreshToken(newToken models.RefreshToken) (*models.RefreshToken, error) {
+	result := r.db.Create(&newToken)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &newToken, nil
+}
+
+func (r *AuthRepository) FindRefreshToken(tokenHash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	result := r.db.First(&token, "token_hash = ?", tokenHash)
+	if result.Error != nil {
+		if result.Error.Error() == "record not found" {
+			return nil, errors.New("Invalid refresh token")
+		}
+		return nil, result.Error
+	}
+	return &token, nil
+}
+
+func (r *AuthRepository) MarkRefreshTokenUsed(tokenId uint, usedAt time.Time) (bool, error) {
+	result := r.db.Model(&models.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL", tokenId).
+		Update("used_at", usedAt)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *AuthRepository) RevokeRefreshTokenFamily(familyId string, revokedAt time.Time) error {
+	return r.db.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyId).
+		Update("revoked_at", revokedAt).Error
+}
+
+func (r *AuthRepository) RevokeUserRefreshTokens(userId uint, revokedAt time.Time) error {
+	return r.db.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userId).
+		Update("revoked_at", revokedAt).Error
+}
+
+func (r *AuthRepository) RevokeToken(revokedToken models.RevokedToken) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&revokedToken).Error
+}
+
+func (r *AuthRepository) IsTokenRevoked(jti string) (bool, error) {
+	var count int64
+	result := r.db.Model(&models.RevokedToken{}).Where("jti = ?", jti).Count(&count)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return count > 0, nil
+}
+
+func (r *AuthRepository) DeleteExpiredRevokedTokens(now time.Time) (int64, error) {
+	result := r.db.Where("expires_at < ?", now).Delete(&models.RevokedToken{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+func (r *AuthRepository) IncrementTokenVersion(userId uint) error {
+	result := r.db.Model(&models.User{}).Where("id = ?", userId).
+		UpdateColumn("token_version", gorm.Expr("token_version + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("User not found")
+	}
+	return nil
+}
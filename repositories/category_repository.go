@@ -0,0 +1,124 @@
+package repositories
+
+import (
+	"errors"
+	"gin-fleamarket/models"
+
+	"gorm.io/gorm"
+)
+
+type ICategoryRepository interface {
+	FindAll() (*[]models.Category, error)
+	FindById(categoryId uint) (*models.Category, error)
+	Create(newCategory models.Category) (*models.Category, error)
+	Update(updateCategory models.Category) (*models.Category, error)
+	Delete(categoryId uint) error
+}
+
+type CategoryMemoryRepository struct {
+	categories []models.Category
+}
+
+func NewCategoryMemoryRepository(categories []models.Category) ICategoryRepository {
+	return &CategoryMemoryRepository{categories: categories}
+}
+
+func (r *CategoryMemoryRepository) FindAll() (*[]models.Category, error) {
+	return &r.categories, nil
+}
+
+func (r *CategoryMemoryRepository) FindById(categoryId uint) (*models.Category, error) {
+	for _, v := range r.categories {
+		if v.ID == categoryId {
+			return &v, nil
+		}
+	}
+	return nil, errors.New("Category not found")
+}
+
+func (r *CategoryMemoryRepository) Create(newCategory models.Category) (*models.Category, error) {
+	newCategory.ID = uint(len(r.categories) + 1)
+	r.categories = append(r.categories, newCategory)
+	return &newCategory, nil
+}
+
+func (r *CategoryMemoryRepository) Update(updateCategory models.Category) (*models.Category, error) {
+	for i, v := range r.categories {
+		if v.ID == updateCategory.ID {
+			r.categories[i] = updateCategory
+			return &r.categories[i], nil
+		}
+	}
+	return nil, errors.New("Category not found")
+}
+
+func (r *CategoryMemoryRepository) Delete(categoryId uint) error {
+	for i, v := range r.categories {
+		if v.ID == categoryId {
+			r.categories = append(r.categories[:i], r.categories[i+1:]...)
+			return nil
+		}
+	}
+	return errors.New("Category not found")
+}
+
+type CategoryRepository struct {
+	db *gorm.DB
+}
+
+func NewCategoryRepository(db *gorm.DB) ICategoryRepository {
+	return &CategoryRepository{db: db}
+}
+
+// FindAll implements ICategoryRepository.
+func (r *CategoryRepository) FindAll() (*[]models.Category, error) {
+	var categories []models.Category
+	result := r.db.Order("id").Find(&categories)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &categories, nil
+}
+
+// FindById implements ICategoryRepository.
+func (r *CategoryRepository) FindById(categoryId uint) (*models.Category, error) {
+	var category models.Category
+	result := r.db.First(&category, "id = ?", categoryId)
+	if result.Error != nil {
+		if result.Error.Error() == "record not found" {
+			return nil, errors.New("Category not found")
+		}
+		return nil, result.Error
+	}
+	return &category, nil
+}
+
+// Create implements ICategoryRepository.
+func (r *CategoryRepository) Create(newCategory models.Category) (*models.Category, error) {
+	result := r.db.Create(&newCategory)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &newCategory, nil
+}
+
+// Update implements ICategoryRepository.
+func (r *CategoryRepository) Update(updateCategory models.Category) (*models.Category, error) {
+	result := r.db.Omit("Children").Save(&updateCategory)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &updateCategory, nil
+}
+
+// Delete implements ICategoryRepository.
+func (r *CategoryRepository) Delete(categoryId uint) error {
+	result := r.db.Delete(&models.Category{}, categoryId)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("Category not found")
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package repositories
+
+import (
+	"errors"
+	"gin-fleamarket/models"
+
+	"gorm.io/gorm"
+)
+
+type IItemCommentRepository interface {
+	// FindByItem はアイテムのコメントを投稿順に返します
+	FindByItem(itemId uint, limit int, offset int) (*[]models.ItemComment, int64, error)
+	FindById(itemId uint, commentId uint) (*models.ItemComment, error)
+	Create(newComment models.ItemComment) (*models.ItemComment, error)
+	// Delete はコメントと、そのコメントへの回答を削除します
+	Delete(commentId uint) error
+}
+
+type ItemCommentRepository struct {
+	db *gorm.DB
+}
+
+func NewItemCommentRepository(db *gorm.DB) IItemCommentRepository {
+	return &ItemCommentRepository{db: db}
+}
+
+// FindByItem implements IItemCommentRepository.
+func (r *ItemCommentRepository) FindByItem(itemId uint, limit int, offset int) (*[]models.ItemComment, int64, error) {
+	query := r.db.Model(&models.ItemComment{}).Where("item_id = ?", itemId)
+
+	var total int64
+	if result := query.Session(&gorm.Session{}).Count(&total); result.Error != nil {
+		return nil, 0, result.Error
+	}
+
+	var comments []models.ItemComment
+	result := query.Order("id").Limit(limit).Offset(offset).Find(&comments)
+	if result.Error != nil {
+		return nil, 0, result.Error
+	}
+	return &comments, total, nil
+}
+
+// FindById implements IItemCommentRepository.
+func (r *ItemCommentRepository) FindById(itemId uint, commentId uint) (*models.ItemComment, error) {
+	var comment models.ItemComment
+	result := r.db.First(&comment, "id = ? AND item_id = ?", commentId, itemId)
+	if result.Error != nil {
+		if result.Error.Error() == "record not found" {
+			return nil, errors.New("Comment not found")
+		}
+		return nil, result.Error
+	}
+	return &comment, nil
+}
+
+// Create implements IItemCommentRepository.
+func (r *ItemCommentRepository) Create(newComment models.ItemComment) (*models.ItemComment, error) {
+	result := r.db.Create(&newComment)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &newComment, nil
+}
+
+// Delete implements IItemCommentRepository.
+func (r *ItemCommentRepository) Delete(commentId uint) error {
+	result := r.db.Where("id = ? OR parent_id = ?", commentId, commentId).Delete(&models.ItemComment{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("Comment not found")
+	}
+	return nil
+}
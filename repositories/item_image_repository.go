@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"errors"
+	"gin-fleamarket/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IItemImageRepository interface {
+	// Transaction は fn に渡したリポジトリの操作を1つのトランザクションで実行します
+	Transaction(fn func(tx IItemImageRepository) error) error
+	// LockItem は同じアイテムへの画像の追加が同時に行われないように、アイテムの行ロックを取ります
+	LockItem(itemId uint) (*models.Item, error)
+	FindByItem(itemId uint) (*[]models.ItemImage, error)
+	Create(newImage models.ItemImage) (*models.ItemImage, error)
+	UpdatePositions(images []models.ItemImage) error
+	Delete(imageId uint) error
+}
+
+type ItemImageRepository struct {
+	db *gorm.DB
+}
+
+func NewItemImageRepository(db *gorm.DB) IItemImageRepository {
+	return &ItemImageRepository{db: db}
+}
+
+// Transaction implements IItemImageRepository.
+func (r *ItemImageRepository) Transaction(fn func(tx IItemImageRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&ItemImageRepository{db: tx})
+	})
+}
+
+// LockItem implements IItemImageRepository.
+func (r *ItemImageRepository) LockItem(itemId uint) (*models.Item, error) {
+	var item models.Item
+	result := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&item, "id = ?", itemId)
+	if result.Error != nil {
+		if result.Error.Error() == "record not found" {
+			return nil, errors.New("Item not found")
+		}
+		return nil, result.Error
+	}
+	return &item, nil
+}
+
+// FindByItem implements IItemImageRepository.
+func (r *ItemImageRepository) FindByItem(itemId uint) (*[]models.ItemImage, error) {
+	var images []models.ItemImage
+	result := r.db.Where("item_id = ?", itemId).Order("position").Find(&images)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &images, nil
+}
+
+// Create implements IItemImageRepository.
+func (r *ItemImageRepository) Create(newImage models.ItemImage) (*models.ItemImage, error) {
+	result := r.db.Create(&newImage)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &newImage, nil
+}
+
+// UpdatePositions implements IItemImageRepository.
+func (r *ItemImageRepository) UpdatePositions(images []models.ItemImage) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, image := range images {
+			result := tx.Model(&models.ItemImage{}).Where("id = ?", image.ID).Update("position", image.Position)
+			if result.Error != nil {
+				return result.Error
+			}
+		}
+		return nil
+	})
+}
+
+// Delete implements IItemImageRepository.
+func (r *ItemImageRepository) Delete(imageId uint) error {
+	result := r.db.Delete(&models.ItemImage{}, imageId)
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
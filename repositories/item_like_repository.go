@@ -0,0 +1,118 @@
+package repositories
+
+import (
+	"errors"
+	"gin-fleamarket/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IItemLikeRepository interface {
+	// Like はいいねを追加し、追加後のアイテムのいいね数を返します。既にいいねしている場合は件数を変えません
+	Like(userId uint, itemId uint) (uint, error)
+	// Unlike はいいねを取り消し、取り消し後のアイテムのいいね数を返します。いいねしていない場合は件数を変えません
+	Unlike(userId uint, itemId uint) (uint, error)
+	// FindByUser はユーザーのいいねを新しい順に返します
+	FindByUser(userId uint, limit int, offset int) (*[]models.ItemLike, int64, error)
+	// FindItems は削除済みのアイテムも含めて返します
+	FindItems(itemIds []uint) (*[]models.Item, error)
+}
+
+type ItemLikeRepository struct {
+	db *gorm.DB
+}
+
+func NewItemLikeRepository(db *gorm.DB) IItemLikeRepository {
+	return &ItemLikeRepository{db: db}
+}
+
+// Like implements IItemLikeRepository.
+func (r *ItemLikeRepository) Like(userId uint, itemId uint) (uint, error) {
+	var likeCount uint
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		like := models.ItemLike{UserID: userId, ItemID: itemId}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&like)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 1 {
+			if err := addLikeCount(tx, itemId, "+"); err != nil {
+				return err
+			}
+		}
+		return findLikeCount(tx, itemId, &likeCount)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return likeCount, nil
+}
+
+// Unlike implements IItemLikeRepository.
+func (r *ItemLikeRepository) Unlike(userId uint, itemId uint) (uint, error) {
+	var likeCount uint
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("user_id = ? AND item_id = ?", userId, itemId).Delete(&models.ItemLike{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 1 {
+			if err := addLikeCount(tx, itemId, "-"); err != nil {
+				return err
+			}
+		}
+		return findLikeCount(tx, itemId, &likeCount)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return likeCount, nil
+}
+
+// FindByUser implements IItemLikeRepository.
+func (r *ItemLikeRepository) FindByUser(userId uint, limit int, offset int) (*[]models.ItemLike, int64, error) {
+	query := r.db.Model(&models.ItemLike{}).Where("user_id = ?", userId)
+
+	var total int64
+	if result := query.Session(&gorm.Session{}).Count(&total); result.Error != nil {
+		return nil, 0, result.Error
+	}
+
+	var likes []models.ItemLike
+	result := query.Order("id DESC").Limit(limit).Offset(offset).Find(&likes)
+	if result.Error != nil {
+		return nil, 0, result.Error
+	}
+	return &likes, total, nil
+}
+
+// FindItems implements IItemLikeRepository.
+func (r *ItemLikeRepository) FindItems(itemIds []uint) (*[]models.Item, error) {
+	var items []models.Item
+	result := r.db.Unscoped().Scopes(preloadImages, preloadAuction).Where("id IN ?", itemIds).Find(&items)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &items, nil
+}
+
+// addLikeCount はアイテムのいいね数を読み込まずに1つ増減するので、同時にいいねされても件数がずれません
+// 削除済みのアイテムでも件数を合わせるため、論理削除されたアイテムも対象にします
+func addLikeCount(tx *gorm.DB, itemId uint, op string) error {
+	return tx.Unscoped().Model(&models.Item{}).Where("id = ?", itemId).
+		UpdateColumn("like_count", gorm.Expr("like_count "+op+" 1")).Error
+}
+
+func findLikeCount(tx *gorm.DB, itemId uint, likeCount *uint) error {
+	var item models.Item
+	result := tx.Unscoped().Select("like_count").First(&item, "id = ?", itemId)
+	if result.Error != nil {
+		if result.Error.Error() == "record not found" {
+			return errors.New("Item not found")
+		}
+		return result.Error
+	}
+	*likeCount = item.LikeCount
+	return nil
+}
@@ -3,16 +3,63 @@ package repositories
 import (
 	"errors"
 	"gin-fleamarket/models"
+	"slices"
+	"sort"
+	"time"
 
 	"gorm.io/gorm"
 )
 
+const (
+	ItemSortNewest    = "newest"
+	ItemSortPriceAsc  = "price_asc"
+	ItemSortPriceDesc = "price_desc"
+)
+
+// ItemCursor はキーセットページネーションで前ページの最後のアイテムを表します
+type ItemCursor struct {
+	Sort  string `json:"s"`
+	Price uint   `json:"p"`
+	ID    uint   `json:"id"`
+}
+
+type ItemFilter struct {
+	MinPrice *uint
+	MaxPrice *uint
+	// Statuses が空でない場合はいずれかのステータスのアイテムだけを返します
+	Statuses []string
+	UserID   *uint
+	// CategoryIDs が空でない場合はいずれかのカテゴリに属するアイテムだけを返します
+	CategoryIDs []uint
+	Sort        string
+	Cursor      *ItemCursor
+	Limit       int
+	Offset      int
+}
+
+type ItemPage struct {
+	Items      []models.Item
+	Total      int64
+	NextCursor *ItemCursor
+}
+
 type IItemRepository interface {
-	FindAll() (*[]models.Item, error)
+	FindAll(filter ItemFilter) (*ItemPage, error)
 	FindById(itemId uint, userId uint) (*models.Item, error)
+	FindVisibleById(itemId uint, statuses []string) (*models.Item, error)
+	FindVisibleByIds(itemIds []uint, statuses []string) (*[]models.Item, error)
+	// CountByCategory は復元できるようにゴミ箱にあるアイテムも含めて数えます
+	CountByCategory(categoryId uint) (int64, error)
+	CountByStatus(userId uint) (map[string]int64, error)
 	Create(newItem models.Item) (*models.Item, error)
 	Update(updateItem models.Item) (*models.Item, error)
-	Delete(itemId uint, userId uint) error
+	Delete(itemId uint, userId uint, version uint) error
+	FindTrash(userId uint, limit int, offset int) (*[]models.Item, int64, error)
+	Restore(itemId uint, userId uint) (*models.Item, error)
+	FindDeletedBefore(before time.Time) (*[]models.Item, error)
+	Purge(itemId uint) error
+	// HasActiveTrade は完了・取り消しされていない注文か、承諾済みのオファーがあるかを返します
+	HasActiveTrade(itemId uint) (bool, error)
 }
 
 type ItemMemoryRepository struct {
@@ -20,31 +67,153 @@ type ItemMemoryRepository struct {
 }
 
 func NewItemMemoryRepository(items []models.Item) IItemRepository {
+	// DB のデフォルト値と同じくバージョンは1から始める
+	for i := range items {
+		items[i].Version = max(items[i].Version, 1)
+	}
 	return &ItemMemoryRepository{items: items}
 }
 
-func (r *ItemMemoryRepository) FindAll() (*[]models.Item, error) {
-	return &r.items, nil
+func (r *ItemMemoryRepository) FindAll(filter ItemFilter) (*ItemPage, error) {
+	matched := []models.Item{}
+	for _, v := range r.items {
+		if v.DeletedAt.Valid {
+			continue
+		}
+		if filter.MinPrice != nil && v.Price < *filter.MinPrice {
+			continue
+		}
+		if filter.MaxPrice != nil && v.Price > *filter.MaxPrice {
+			continue
+		}
+		if len(filter.Statuses) > 0 && !slices.Contains(filter.Statuses, v.Status) {
+			continue
+		}
+		if filter.UserID != nil && v.UserID != *filter.UserID {
+			continue
+		}
+		if len(filter.CategoryIDs) > 0 && (v.CategoryID == nil || !slices.Contains(filter.CategoryIDs, *v.CategoryID)) {
+			continue
+		}
+		matched = append(matched, v)
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		return itemLess(filter.Sort, matched[i], matched[j])
+	})
+	total := int64(len(matched))
+
+	start := 0
+	if filter.Cursor != nil {
+		start = len(matched)
+		for i, v := range matched {
+			if itemLess(filter.Sort, models.Item{Model: gorm.Model{ID: filter.Cursor.ID}, Price: filter.Cursor.Price}, v) {
+				start = i
+				break
+			}
+		}
+	} else {
+		start = min(filter.Offset, len(matched))
+	}
+	end := min(start+filter.Limit, len(matched))
+
+	page := &ItemPage{Items: matched[start:end], Total: total}
+	if end < len(matched) && end > start {
+		page.NextCursor = newItemCursor(filter.Sort, matched[end-1])
+	}
+	return page, nil
+}
+
+// itemLess は並び順において a が b より前に来るかを返します
+func itemLess(sortKey string, a models.Item, b models.Item) bool {
+	switch sortKey {
+	case ItemSortPriceAsc:
+		if a.Price != b.Price {
+			return a.Price < b.Price
+		}
+		return a.ID < b.ID
+	case ItemSortPriceDesc:
+		if a.Price != b.Price {
+			return a.Price > b.Price
+		}
+		return a.ID > b.ID
+	default:
+		return a.ID > b.ID
+	}
+}
+
+func newItemCursor(sortKey string, last models.Item) *ItemCursor {
+	return &ItemCursor{Sort: sortKey, Price: last.Price, ID: last.ID}
 }
 
 func (r *ItemMemoryRepository) FindById(itemId uint, userId uint) (*models.Item, error) {
 	for _, v := range r.items {
-		if v.ID == itemId {
+		if v.ID == itemId && v.UserID == userId && !v.DeletedAt.Valid {
 			return &v, nil
 		}
 	}
-	return nil, errors.New("item not found")
+	return nil, errors.New("Item not found")
+}
+
+func (r *ItemMemoryRepository) FindVisibleById(itemId uint, statuses []string) (*models.Item, error) {
+	for _, v := range r.items {
+		if v.ID == itemId && slices.Contains(statuses, v.Status) && !v.DeletedAt.Valid {
+			return &v, nil
+		}
+	}
+	return nil, errors.New("Item not found")
+}
+
+func (r *ItemMemoryRepository) FindVisibleByIds(itemIds []uint, statuses []string) (*[]models.Item, error) {
+	items := []models.Item{}
+	for _, itemId := range itemIds {
+		for _, v := range r.items {
+			if v.ID == itemId && slices.Contains(statuses, v.Status) && !v.DeletedAt.Valid {
+				items = append(items, v)
+			}
+		}
+	}
+	return &items, nil
+}
+
+func (r *ItemMemoryRepository) CountByCategory(categoryId uint) (int64, error) {
+	var count int64
+	for _, v := range r.items {
+		if v.CategoryID != nil && *v.CategoryID == categoryId {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *ItemMemoryRepository) CountByStatus(userId uint) (map[string]int64, error) {
+	counts := map[string]int64{}
+	for _, v := range r.items {
+		if v.UserID == userId && !v.DeletedAt.Valid {
+			counts[v.Status]++
+		}
+	}
+	return counts, nil
 }
 
 func (r *ItemMemoryRepository) Create(newItem models.Item) (*models.Item, error) {
-	newItem.ID = uint(len(r.items) + 1)
+	// 完全削除したアイテムがあってもIDが重複しないように最大値から採番する
+	newItem.ID = 1
+	for _, v := range r.items {
+		newItem.ID = max(newItem.ID, v.ID+1)
+	}
+	newItem.Version = max(newItem.Version, 1)
 	r.items = append(r.items, newItem)
 	return &newItem, nil
 }
 
 func (r *ItemMemoryRepository) Update(updateItem models.Item) (*models.Item, error) {
 	for i, v := range r.items {
-		if v.ID == updateItem.ID {
+		if v.ID == updateItem.ID && !v.DeletedAt.Valid {
+			if v.Version != updateItem.Version {
+				return nil, errors.New("Version conflict")
+			}
+			updateItem.Version++
 			r.items[i] = updateItem
 			return &r.items[i], nil
 		}
@@ -52,9 +221,61 @@ func (r *ItemMemoryRepository) Update(updateItem models.Item) (*models.Item, err
 	return nil, errors.New("Unexpected error")
 }
 
-func (r *ItemMemoryRepository) Delete(itemId uint, userId uint) error {
+// Delete は ItemRepository と同じく DeletedAt を設定する論理削除です
+func (r *ItemMemoryRepository) Delete(itemId uint, userId uint, version uint) error {
 	for i, v := range r.items {
-		if v.ID == itemId {
+		if v.ID == itemId && v.UserID == userId && !v.DeletedAt.Valid {
+			if v.Version != version {
+				return errors.New("Version conflict")
+			}
+			r.items[i].DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+			return nil
+		}
+	}
+	return errors.New("Item not found")
+}
+
+func (r *ItemMemoryRepository) FindTrash(userId uint, limit int, offset int) (*[]models.Item, int64, error) {
+	trash := []models.Item{}
+	for _, v := range r.items {
+		if v.UserID == userId && v.DeletedAt.Valid {
+			trash = append(trash, v)
+		}
+	}
+	sort.Slice(trash, func(i, j int) bool {
+		return trash[i].DeletedAt.Time.After(trash[j].DeletedAt.Time)
+	})
+
+	total := int64(len(trash))
+	start := min(offset, len(trash))
+	end := min(start+limit, len(trash))
+	page := trash[start:end]
+	return &page, total, nil
+}
+
+func (r *ItemMemoryRepository) Restore(itemId uint, userId uint) (*models.Item, error) {
+	for i, v := range r.items {
+		if v.ID == itemId && v.UserID == userId && v.DeletedAt.Valid {
+			r.items[i].DeletedAt = gorm.DeletedAt{}
+			return &r.items[i], nil
+		}
+	}
+	return nil, errors.New("Item not found")
+}
+
+func (r *ItemMemoryRepository) FindDeletedBefore(before time.Time) (*[]models.Item, error) {
+	items := []models.Item{}
+	for _, v := range r.items {
+		if v.DeletedAt.Valid && v.DeletedAt.Time.Before(before) {
+			items = append(items, v)
+		}
+	}
+	return &items, nil
+}
+
+func (r *ItemMemoryRepository) Purge(itemId uint) error {
+	for i, v := range r.items {
+		if v.ID == itemId && v.DeletedAt.Valid {
 			// スライスの要素を削除する操作
 			// サンプル https://go.dev/play/p/pA4u3eSoLT3
 			r.items = append(r.items[:i], r.items[i+1:]...)
@@ -64,6 +285,10 @@ func (r *ItemMemoryRepository) Delete(itemId uint, userId uint) error {
 	return errors.New("Item not found")
 }
 
+func (r *ItemMemoryRepository) HasActiveTrade(itemId uint) (bool, error) {
+	return false, nil
+}
+
 type ItemRepository struct {
 	db *gorm.DB
 }
@@ -78,32 +303,86 @@ func (r *ItemRepository) Create(newItem models.Item) (*models.Item, error) {
 }
 
 // Delete implements IItemRepository.
-func (r *ItemRepository) Delete(itemId uint, userId uint) error {
+// 読み込み後に他のリクエストで更新されていた場合は "Version conflict" を返します
+func (r *ItemRepository) Delete(itemId uint, userId uint, version uint) error {
 	deleteItem, err := r.FindById(itemId, userId)
 	if err != nil {
 		return err
 	}
-	result := r.db.Delete(&deleteItem)
+	result := r.db.Where("version = ?", version).Delete(&deleteItem)
 	if result.Error != nil {
 		return result.Error
 	}
+	if result.RowsAffected == 0 {
+		return errors.New("Version conflict")
+	}
 	return nil
 }
 
 // FindAll implements IItemRepository.
-func (r *ItemRepository) FindAll() (*[]models.Item, error) {
+func (r *ItemRepository) FindAll(filter ItemFilter) (*ItemPage, error) {
+	query := r.db.Model(&models.Item{})
+	if filter.MinPrice != nil {
+		query = query.Where("price >= ?", *filter.MinPrice)
+	}
+	if filter.MaxPrice != nil {
+		query = query.Where("price <= ?", *filter.MaxPrice)
+	}
+	if len(filter.Statuses) > 0 {
+		query = query.Where("status IN ?", filter.Statuses)
+	}
+	if filter.UserID != nil {
+		query = query.Where("user_id = ?", *filter.UserID)
+	}
+	if len(filter.CategoryIDs) > 0 {
+		query = query.Where("category_id IN ?", filter.CategoryIDs)
+	}
+
+	var total int64
+	if result := query.Session(&gorm.Session{}).Count(&total); result.Error != nil {
+		return nil, result.Error
+	}
+
+	switch filter.Sort {
+	case ItemSortPriceAsc:
+		query = query.Order("price ASC").Order("id ASC")
+		if c := filter.Cursor; c != nil {
+			query = query.Where("price > ? OR (price = ? AND id > ?)", c.Price, c.Price, c.ID)
+		}
+	case ItemSortPriceDesc:
+		query = query.Order("price DESC").Order("id DESC")
+		if c := filter.Cursor; c != nil {
+			query = query.Where("price < ? OR (price = ? AND id < ?)", c.Price, c.Price, c.ID)
+		}
+	default:
+		query = query.Order("id DESC")
+		if c := filter.Cursor; c != nil {
+			query = query.Where("id < ?", c.ID)
+		}
+	}
+	if filter.Cursor == nil && filter.Offset > 0 {
+		query = query.Offset(filter.Offset)
+	}
+
+	// 次ページの有無を判定するために1件多く取得する
 	var items []models.Item
-	result := r.db.Find(&items)
+	result := query.Scopes(preloadImages, preloadAuction).Limit(filter.Limit + 1).Find(&items)
 	if result.Error != nil {
 		return nil, result.Error
 	}
-	return &items, nil
+
+	page := &ItemPage{Items: items, Total: total}
+	if len(items) > filter.Limit {
+		page.Items = items[:filter.Limit]
+		page.NextCursor = newItemCursor(filter.Sort, page.Items[filter.Limit-1])
+	}
+	return page, nil
 }
 
 // FindById implements IItemRepository.
 func (r *ItemRepository) FindById(itemId uint, userId uint) (*models.Item, error) {
 	var item models.Item
-	result := r.db.First(&item, "id = ? AND user_id = ?", itemId, userId)
+	result := r.db.Scopes(preloadImages, preloadAuction).First(&item, "id = ? AND user_id = ?", itemId, userId)
 
 	if result.Error != nil {
 		if result.Error.Error() == "record not found" {
@@ -114,15 +393,192 @@ func (r *ItemRepository) FindById(itemId uint, userId uint) (*models.Item, error
 	return &item, nil
 }
 
+// FindVisibleById implements IItemRepository.
+func (r *ItemRepository) FindVisibleById(itemId uint, statuses []string) (*models.Item, error) {
+	var item models.Item
+	result := r.db.Scopes(preloadImages, preloadAuction).First(&item, "id = ? AND status IN ?", itemId, statuses)
+
+	if result.Error != nil {
+		if result.Error.Error() == "record not found" {
+			return nil, errors.New("Item not found")
+		}
+		return nil, result.Error
+	}
+	return &item, nil
+}
+
+// FindVisibleByIds implements IItemRepository.
+func (r *ItemRepository) FindVisibleByIds(itemIds []uint, statuses []string) (*[]models.Item, error) {
+	var items []models.Item
+	result := r.db.Scopes(preloadImages, preloadAuction).Where("id IN ? AND status IN ?", itemIds, statuses).Find(&items)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &items, nil
+}
+
+// CountByCategory implements IItemRepository.
+func (r *ItemRepository) CountByCategory(categoryId uint) (int64, error) {
+	var count int64
+	result := r.db.Unscoped().Model(&models.Item{}).Where("category_id = ?", categoryId).Count(&count)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return count, nil
+}
+
+// CountByStatus implements IItemRepository.
+func (r *ItemRepository) CountByStatus(userId uint) (map[string]int64, error) {
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	result := r.db.Model(&models.Item{}).
+		Select("status, COUNT(*) AS count").
+		Where("user_id = ?", userId).
+		Group("status").
+		Scan(&rows)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	counts := map[string]int64{}
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
+
 // Update implements IItemRepository.
+// updateItem.Version が保存されているバージョンと一致する場合だけ更新し、バージョンを1つ進めます
 func (r *ItemRepository) Update(updateItem models.Item) (*models.Item, error) {
-	result := r.db.Save(&updateItem)
+	expectedVersion := updateItem.Version
+	updateItem.Version++
+	result := r.db.Model(&updateItem).
+		Where("version = ?", expectedVersion).
+		Select("*").Omit("CreatedAt", "DeletedAt", "Images", "Auction", "LikeCount").
+		Updates(&updateItem)
 	if result.Error != nil {
 		return nil, result.Error
 	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("Version conflict")
+	}
 	return &updateItem, nil
 }
 
+// FindTrash implements IItemRepository.
+func (r *ItemRepository) FindTrash(userId uint, limit int, offset int) (*[]models.Item, int64, error) {
+	query := r.db.Unscoped().Model(&models.Item{}).Where("user_id = ? AND deleted_at IS NOT NULL", userId)
+
+	var total int64
+	if result := query.Session(&gorm.Session{}).Count(&total); result.Error != nil {
+		return nil, 0, result.Error
+	}
+
+	var items []models.Item
+	result := query.Scopes(preloadImages, preloadAuction).Order("deleted_at DESC").Limit(limit).Offset(offset).Find(&items)
+	if result.Error != nil {
+		return nil, 0, result.Error
+	}
+	return &items, total, nil
+}
+
+// Restore implements IItemRepository.
+func (r *ItemRepository) Restore(itemId uint, userId uint) (*models.Item, error) {
+	result := r.db.Unscoped().Model(&models.Item{}).
+		Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", itemId, userId).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("Item not found")
+	}
+	return r.FindById(itemId, userId)
+}
+
+// FindDeletedBefore implements IItemRepository.
+// 注文から参照されているアイテムは、取引の記録を残すため物理削除の対象にしません
+func (r *ItemRepository) FindDeletedBefore(before time.Time) (*[]models.Item, error) {
+	var items []models.Item
+	orderedItemIds := r.db.Unscoped().Model(&models.Order{}).Select("item_id")
+	result := r.db.Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+		Where("id NOT IN (?)", orderedItemIds).
+		Find(&items)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &items, nil
+}
+
+// Purge implements IItemRepository.
+// 論理削除済みのアイテムを画像のレコードと一緒に物理削除します
+func (r *ItemRepository) Purge(itemId uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("item_id = ?", itemId).Delete(&models.ItemImage{}).Error; err != nil {
+			return err
+		}
+		auctionIds := tx.Unscoped().Model(&models.Auction{}).Select("id").Where("item_id = ?", itemId)
+		if err := tx.Unscoped().Where("auction_id IN (?)", auctionIds).Delete(&models.Bid{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("item_id = ?", itemId).Delete(&models.Auction{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("item_id = ?", itemId).Delete(&models.ItemComment{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("item_id = ?", itemId).Delete(&models.ItemLike{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("item_id = ?", itemId).Delete(&models.Offer{}).Error; err != nil {
+			return err
+		}
+		orderedItemIds := tx.Unscoped().Model(&models.Order{}).Select("item_id")
+		result := tx.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", itemId).Where("id NOT IN (?)", orderedItemIds).Delete(&models.Item{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("Item not found")
+		}
+		return nil
+	})
+}
+
+// HasActiveTrade implements IItemRepository.
+func (r *ItemRepository) HasActiveTrade(itemId uint) (bool, error) {
+	var count int64
+	result := r.db.Model(&models.Order{}).
+		Where("item_id = ? AND status NOT IN ?", itemId, []string{models.OrderStatusCompleted, models.OrderStatusFailed, models.OrderStatusCancelled}).
+		Count(&count)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if count > 0 {
+		return true, nil
+	}
+	result = r.db.Model(&models.Offer{}).Where("item_id = ? AND status = ?", itemId, models.OfferStatusAccepted).Count(&count)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return count > 0, nil
+}
+
 func NewItemRepository(db *gorm.DB) IItemRepository {
 	return &ItemRepository{db: db}
 }
+
+// preloadImages はアイテムの画像を表示順で読み込みます
+func preloadImages(db *gorm.DB) *gorm.DB {
+	return db.Preload("Images", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position")
+	})
+}
+
+// preloadAuction はオークション形式の出品の状態を読み込みます
+func preloadAuction(db *gorm.DB) *gorm.DB {
+	return db.Preload("Auction")
+}
@@ -0,0 +1,98 @@
+package repositories
+
+import (
+	"gin-fleamarket/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UnreadCount は注文ごとの未読メッセージの件数です
+type UnreadCount struct {
+	OrderID uint
+	Count   int64
+}
+
+type IMessageRepository interface {
+	Create(newMessage models.OrderMessage) (*models.OrderMessage, error)
+	// FindByOrder は注文のメッセージを新しい順に返します
+	FindByOrder(orderId uint, limit int, offset int) (*[]models.OrderMessage, int64, error)
+	// MarkRead は注文で recipientId が受信した未読メッセージを既読にし、既読にした件数を返します
+	MarkRead(orderId uint, recipientId uint, readAt time.Time) (int64, error)
+	// CountUnread は recipientId が受信した未読メッセージの件数を注文ごとに返します
+	CountUnread(recipientId uint) (*[]UnreadCount, error)
+	// CountUnreadByOrder は注文で recipientId が受信した未読メッセージの件数を返します
+	CountUnreadByOrder(orderId uint, recipientId uint) (int64, error)
+}
+
+type MessageRepository struct {
+	db *gorm.DB
+}
+
+func NewMessageRepository(db *gorm.DB) IMessageRepository {
+	return &MessageRepository{db: db}
+}
+
+// Create implements IMessageRepository.
+func (r *MessageRepository) Create(newMessage models.OrderMessage) (*models.OrderMessage, error) {
+	result := r.db.Create(&newMessage)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &newMessage, nil
+}
+
+// FindByOrder implements IMessageRepository.
+func (r *MessageRepository) FindByOrder(orderId uint, limit int, offset int) (*[]models.OrderMessage, int64, error) {
+	query := r.db.Model(&models.OrderMessage{}).Where("order_id = ?", orderId)
+
+	var total int64
+	if result := query.Session(&gorm.Session{}).Count(&total); result.Error != nil {
+		return nil, 0, result.Error
+	}
+
+	var messages []models.OrderMessage
+	result := query.Order("id DESC").Limit(limit).Offset(offset).Find(&messages)
+	if result.Error != nil {
+		return nil, 0, result.Error
+	}
+	return &messages, total, nil
+}
+
+// MarkRead implements IMessageRepository.
+func (r *MessageRepository) MarkRead(orderId uint, recipientId uint, readAt time.Time) (int64, error) {
+	result := r.db.Model(&models.OrderMessage{}).
+		Where("order_id = ? AND recipient_id = ? AND read_at IS NULL", orderId, recipientId).
+		Update("read_at", readAt)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+// CountUnread implements IMessageRepository.
+func (r *MessageRepository) CountUnread(recipientId uint) (*[]UnreadCount, error) {
+	var counts []UnreadCount
+	result := r.db.Model(&models.OrderMessage{}).
+		Select("order_id, COUNT(*) AS count").
+		Where("recipient_id = ? AND read_at IS NULL", recipientId).
+		Group("order_id").
+		Order("order_id").
+		Scan(&counts)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &counts, nil
+}
+
+// CountUnreadByOrder implements IMessageRepository.
+func (r *MessageRepository) CountUnreadByOrder(orderId uint, recipientId uint) (int64, error) {
+	var count int64
+	result := r.db.Model(&models.OrderMessage{}).
+		Where("order_id = ? AND recipient_id = ? AND read_at IS NULL", orderId, recipientId).
+		Count(&count)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return count, nil
+}
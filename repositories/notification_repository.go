@@ -0,0 +1,72 @@
+package repositories
+
+import (
+	"gin-fleamarket/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type INotificationRepository interface {
+	Create(newNotification models.Notification) (*models.Notification, error)
+	// FindByUser はユーザーへのお知らせを新しい順に返します
+	FindByUser(userId uint, limit int, offset int) (*[]models.Notification, int64, error)
+	CountUnread(userId uint) (int64, error)
+	// MarkRead はユーザーへの未読のお知らせをすべて既読にし、既読にした件数を返します
+	MarkRead(userId uint, readAt time.Time) (int64, error)
+}
+
+type NotificationRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepository(db *gorm.DB) INotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+// Create implements INotificationRepository.
+func (r *NotificationRepository) Create(newNotification models.Notification) (*models.Notification, error) {
+	result := r.db.Create(&newNotification)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &newNotification, nil
+}
+
+// FindByUser implements INotificationRepository.
+func (r *NotificationRepository) FindByUser(userId uint, limit int, offset int) (*[]models.Notification, int64, error) {
+	query := r.db.Model(&models.Notification{}).Where("user_id = ?", userId)
+
+	var total int64
+	if result := query.Session(&gorm.Session{}).Count(&total); result.Error != nil {
+		return nil, 0, result.Error
+	}
+
+	var notifications []models.Notification
+	result := query.Order("id DESC").Limit(limit).Offset(offset).Find(&notifications)
+	if result.Error != nil {
+		return nil, 0, result.Error
+	}
+	return &notifications, total, nil
+}
+
+// CountUnread implements INotificationRepository.
+func (r *NotificationRepository) CountUnread(userId uint) (int64, error) {
+	var count int64
+	result := r.db.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userId).Count(&count)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return count, nil
+}
+
+// MarkRead implements INotificationRepository.
+func (r *NotificationRepository) MarkRead(userId uint, readAt time.Time) (int64, error) {
+	result := r.db.Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userId).
+		Update("read_at", readAt)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,118 @@
+package repositories
+
+import (
+	"errors"
+	"gin-fleamarket/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IOfferRepository interface {
+	FindById(offerId uint) (*models.Offer, error)
+	LockOffer(offerId uint) (*models.Offer, error)
+	// FindByItem はアイテムへの提案を返します。buyerId が nil でない場合はその購入者との交渉だけを返します
+	FindByItem(itemId uint, buyerId *uint) (*[]models.Offer, error)
+	// FindLatest は購入者との交渉のうち、指定したステータスの最新の提案を返します
+	FindLatest(itemId uint, buyerId *uint, status string) (*models.Offer, error)
+	// FindExpiredAccepted は購入の期限を過ぎた承諾済みの提案を返します
+	FindExpiredAccepted(now time.Time) (*[]models.Offer, error)
+	Create(newOffer models.Offer) (*models.Offer, error)
+	Update(updateOffer models.Offer) (*models.Offer, error)
+}
+
+type OfferRepository struct {
+	db *gorm.DB
+}
+
+func NewOfferRepository(db *gorm.DB) IOfferRepository {
+	return &OfferRepository{db: db}
+}
+
+// FindById implements IOfferRepository.
+func (r *OfferRepository) FindById(offerId uint) (*models.Offer, error) {
+	var offer models.Offer
+	result := r.db.First(&offer, "id = ?", offerId)
+	if result.Error != nil {
+		if result.Error.Error() == "record not found" {
+			return nil, errors.New("Offer not found")
+		}
+		return nil, result.Error
+	}
+	return &offer, nil
+}
+
+// LockOffer implements IOfferRepository.
+func (r *OfferRepository) LockOffer(offerId uint) (*models.Offer, error) {
+	var offer models.Offer
+	result := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&offer, "id = ?", offerId)
+	if result.Error != nil {
+		if result.Error.Error() == "record not found" {
+			return nil, errors.New("Offer not found")
+		}
+		return nil, result.Error
+	}
+	return &offer, nil
+}
+
+// FindByItem implements IOfferRepository.
+func (r *OfferRepository) FindByItem(itemId uint, buyerId *uint) (*[]models.Offer, error) {
+	query := r.db.Where("item_id = ?", itemId)
+	if buyerId != nil {
+		query = query.Where("buyer_id = ?", *buyerId)
+	}
+
+	var offers []models.Offer
+	result := query.Order("id DESC").Find(&offers)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &offers, nil
+}
+
+// FindLatest implements IOfferRepository.
+func (r *OfferRepository) FindLatest(itemId uint, buyerId *uint, status string) (*models.Offer, error) {
+	query := r.db.Where("item_id = ? AND status = ?", itemId, status)
+	if buyerId != nil {
+		query = query.Where("buyer_id = ?", *buyerId)
+	}
+
+	var offer models.Offer
+	result := query.Order("id DESC").First(&offer)
+	if result.Error != nil {
+		if result.Error.Error() == "record not found" {
+			return nil, errors.New("Offer not found")
+		}
+		return nil, result.Error
+	}
+	return &offer, nil
+}
+
+// FindExpiredAccepted implements IOfferRepository.
+func (r *OfferRepository) FindExpiredAccepted(now time.Time) (*[]models.Offer, error) {
+	var offers []models.Offer
+	result := r.db.Where("status = ? AND expires_at < ?", models.OfferStatusAccepted, now).Order("id").Find(&offers)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &offers, nil
+}
+
+// Create implements IOfferRepository.
+func (r *OfferRepository) Create(newOffer models.Offer) (*models.Offer, error) {
+	result := r.db.Create(&newOffer)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &newOffer, nil
+}
+
+// Update implements IOfferRepository.
+func (r *OfferRepository) Update(updateOffer models.Offer) (*models.Offer, error) {
+	result := r.db.Save(&updateOffer)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &updateOffer, nil
+}
@@ -0,0 +1,196 @@
+package repositories
+
+import (
+	"errors"
+	"gin-fleamarket/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IOrderRepository interface {
+	// Transaction は fn に渡したリポジトリの操作を1つのトランザクションで実行します
+	Transaction(fn func(tx IOrderRepository) error) error
+	// Wallet は同じトランザクションで台帳を操作するためのリポジトリを返します
+	Wallet() IWalletRepository
+	// Offers は同じトランザクションで値下げ交渉を操作するためのリポジトリを返します
+	Offers() IOfferRepository
+	// Auctions は同じトランザクションでオークションを操作するためのリポジトリを返します
+	Auctions() IAuctionRepository
+	// Search は同じトランザクションで検索インデックスを操作するためのリポジトリを返します
+	Search() ISearchRepository
+	LockItem(itemId uint) (*models.Item, error)
+	UpdateItemStatus(item models.Item, status string) (*models.Item, error)
+	FindById(orderId uint) (*models.Order, error)
+	LockOrder(orderId uint) (*models.Order, error)
+	Create(newOrder models.Order) (*models.Order, error)
+	Update(updateOrder models.Order) (*models.Order, error)
+	CreateEvent(event models.OrderEvent) error
+	FindEvents(orderId uint) (*[]models.OrderEvent, error)
+	// CreateRating は評価を作成し、評価された側のユーザーの評価件数を増やします
+	CreateRating(rating models.OrderRating) (*models.OrderRating, error)
+	FindRatings(orderId uint) (*[]models.OrderRating, error)
+	// CreatePaymentEvent は同じイベントIDが既に記録されている場合は何もせずに false を返します
+	CreatePaymentEvent(event models.PaymentEvent) (bool, error)
+}
+
+type OrderRepository struct {
+	db *gorm.DB
+}
+
+func NewOrderRepository(db *gorm.DB) IOrderRepository {
+	return &OrderRepository{db: db}
+}
+
+// Transaction implements IOrderRepository.
+func (r *OrderRepository) Transaction(fn func(tx IOrderRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&OrderRepository{db: tx})
+	})
+}
+
+// Wallet implements IOrderRepository.
+func (r *OrderRepository) Wallet() IWalletRepository {
+	return NewWalletRepository(r.db)
+}
+
+// Offers implements IOrderRepository.
+func (r *OrderRepository) Offers() IOfferRepository {
+	return NewOfferRepository(r.db)
+}
+
+// Auctions implements IOrderRepository.
+func (r *OrderRepository) Auctions() IAuctionRepository {
+	return NewAuctionRepository(r.db)
+}
+
+// Search implements IOrderRepository.
+func (r *OrderRepository) Search() ISearchRepository {
+	return NewSearchRepository(r.db)
+}
+
+// LockItem implements IOrderRepository.
+// トランザクションが終わるまで他のトランザクションから同じアイテムを更新できないように行ロックを取ります
+func (r *OrderRepository) LockItem(itemId uint) (*models.Item, error) {
+	var item models.Item
+	result := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&item, "id = ?", itemId)
+	if result.Error != nil {
+		if result.Error.Error() == "record not found" {
+			return nil, errors.New("Item not found")
+		}
+		return nil, result.Error
+	}
+	return &item, nil
+}
+
+// UpdateItemStatus implements IOrderRepository.
+// 行ロックが使えないDBでも二重に更新しないように、読み込んだ時点のバージョンを条件に更新します
+func (r *OrderRepository) UpdateItemStatus(item models.Item, status string) (*models.Item, error) {
+	result := r.db.Model(&models.Item{}).
+		Where("id = ? AND version = ?", item.ID, item.Version).
+		Updates(map[string]interface{}{"status": status, "version": item.Version + 1})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("Version conflict")
+	}
+	item.Status = status
+	item.Version++
+	return &item, nil
+}
+
+// FindById implements IOrderRepository.
+func (r *OrderRepository) FindById(orderId uint) (*models.Order, error) {
+	var order models.Order
+	result := r.db.First(&order, "id = ?", orderId)
+	if result.Error != nil {
+		if result.Error.Error() == "record not found" {
+			return nil, errors.New("Order not found")
+		}
+		return nil, result.Error
+	}
+	return &order, nil
+}
+
+// LockOrder implements IOrderRepository.
+func (r *OrderRepository) LockOrder(orderId uint) (*models.Order, error) {
+	var order models.Order
+	result := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, "id = ?", orderId)
+	if result.Error != nil {
+		if result.Error.Error() == "record not found" {
+			return nil, errors.New("Order not found")
+		}
+		return nil, result.Error
+	}
+	return &order, nil
+}
+
+// Create implements IOrderRepository.
+func (r *OrderRepository) Create(newOrder models.Order) (*models.Order, error) {
+	result := r.db.Create(&newOrder)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &newOrder, nil
+}
+
+// Update implements IOrderRepository.
+func (r *OrderRepository) Update(updateOrder models.Order) (*models.Order, error) {
+	result := r.db.Save(&updateOrder)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &updateOrder, nil
+}
+
+// CreateEvent implements IOrderRepository.
+func (r *OrderRepository) CreateEvent(event models.OrderEvent) error {
+	return r.db.Create(&event).Error
+}
+
+// FindEvents implements IOrderRepository.
+func (r *OrderRepository) FindEvents(orderId uint) (*[]models.OrderEvent, error) {
+	var events []models.OrderEvent
+	result := r.db.Where("order_id = ?", orderId).Order("id").Find(&events)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &events, nil
+}
+
+// CreateRating implements IOrderRepository.
+func (r *OrderRepository) CreateRating(rating models.OrderRating) (*models.OrderRating, error) {
+	column, ok := models.RatingCountColumns[rating.Rating]
+	if !ok {
+		return nil, errors.New("Invalid rating")
+	}
+	result := r.db.Create(&rating)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	result = r.db.Model(&models.User{}).Where("id = ?", rating.RateeID).UpdateColumn(column, gorm.Expr(column+" + 1"))
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &rating, nil
+}
+
+// FindRatings implements IOrderRepository.
+func (r *OrderRepository) FindRatings(orderId uint) (*[]models.OrderRating, error) {
+	var ratings []models.OrderRating
+	result := r.db.Where("order_id = ?", orderId).Order("id").Find(&ratings)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &ratings, nil
+}
+
+// CreatePaymentEvent implements IOrderRepository.
+func (r *OrderRepository) CreatePaymentEvent(event models.PaymentEvent) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&event)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,59 @@
+package repositories
+
+import (
+	"gin-fleamarket/models"
+
+	"gorm.io/gorm"
+)
+
+// Review は評価と、評価した側が取引で購入者と出品者のどちらだったかです
+type Review struct {
+	models.OrderRating
+	RaterRole string
+}
+
+type IReviewRepository interface {
+	// FindByRatee はユーザーが受け取った評価を新しい順に返します
+	// rating が空でない場合はその評価だけを、role が空でない場合はユーザーがその立場で受け取った評価だけを返します
+	FindByRatee(userId uint, rating string, role string, limit int, offset int) (*[]Review, int64, error)
+}
+
+type ReviewRepository struct {
+	db *gorm.DB
+}
+
+func NewReviewRepository(db *gorm.DB) IReviewRepository {
+	return &ReviewRepository{db: db}
+}
+
+// FindByRatee implements IReviewRepository.
+func (r *ReviewRepository) FindByRatee(userId uint, rating string, role string, limit int, offset int) (*[]Review, int64, error) {
+	query := r.db.Model(&models.OrderRating{}).
+		Joins("JOIN orders ON orders.id = order_ratings.order_id").
+		Where("order_ratings.ratee_id = ?", userId)
+	if rating != "" {
+		query = query.Where("order_ratings.rating = ?", rating)
+	}
+	switch role {
+	case "buyer":
+		query = query.Where("orders.buyer_id = ?", userId)
+	case "seller":
+		query = query.Where("orders.seller_id = ?", userId)
+	}
+
+	var total int64
+	if result := query.Session(&gorm.Session{}).Count(&total); result.Error != nil {
+		return nil, 0, result.Error
+	}
+
+	var reviews []Review
+	result := query.
+		Select("order_ratings.*, CASE WHEN orders.buyer_id = order_ratings.rater_id THEN 'buyer' ELSE 'seller' END AS rater_role").
+		Order("order_ratings.id DESC").
+		Limit(limit).Offset(offset).
+		Scan(&reviews)
+	if result.Error != nil {
+		return nil, 0, result.Error
+	}
+	return &reviews, total, nil
+}
@@ -0,0 +1,129 @@
+package repositories
+
+import (
+	"gin-fleamarket/models"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+type SearchHit struct {
+	ItemID uint
+	Score  uint
+}
+
+type ISearchRepository interface {
+	ReplaceTokens(itemId uint, tokens map[string]uint) error
+	DeleteTokens(itemId uint) error
+	// Search は statuses のいずれかのステータスで削除されていないアイテムだけを対象に検索します
+	Search(tokens []string, statuses []string, limit int, offset int) ([]SearchHit, int64, error)
+}
+
+type SearchMemoryRepository struct {
+	tokens         map[uint]map[string]uint
+	itemRepository IItemRepository
+}
+
+func NewSearchMemoryRepository(itemRepository IItemRepository) ISearchRepository {
+	return &SearchMemoryRepository{tokens: map[uint]map[string]uint{}, itemRepository: itemRepository}
+}
+
+func (r *SearchMemoryRepository) ReplaceTokens(itemId uint, tokens map[string]uint) error {
+	r.tokens[itemId] = tokens
+	return nil
+}
+
+func (r *SearchMemoryRepository) DeleteTokens(itemId uint) error {
+	delete(r.tokens, itemId)
+	return nil
+}
+
+func (r *SearchMemoryRepository) Search(tokens []string, statuses []string, limit int, offset int) ([]SearchHit, int64, error) {
+	hits := []SearchHit{}
+	for itemId, itemTokens := range r.tokens {
+		if _, err := r.itemRepository.FindVisibleById(itemId, statuses); err != nil {
+			continue
+		}
+		hit := SearchHit{ItemID: itemId}
+		matched := true
+		for _, token := range tokens {
+			weight, ok := itemTokens[token]
+			if !ok {
+				matched = false
+				break
+			}
+			hit.Score += weight
+		}
+		if matched {
+			hits = append(hits, hit)
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ItemID > hits[j].ItemID
+	})
+
+	total := int64(len(hits))
+	start := min(offset, len(hits))
+	end := min(start+limit, len(hits))
+	return hits[start:end], total, nil
+}
+
+type SearchRepository struct {
+	db *gorm.DB
+}
+
+func NewSearchRepository(db *gorm.DB) ISearchRepository {
+	return &SearchRepository{db: db}
+}
+
+// ReplaceTokens implements ISearchRepository.
+func (r *SearchRepository) ReplaceTokens(itemId uint, tokens map[string]uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("item_id = ?", itemId).Delete(&models.ItemSearchToken{}).Error; err != nil {
+			return err
+		}
+		if len(tokens) == 0 {
+			return nil
+		}
+		rows := make([]models.ItemSearchToken, 0, len(tokens))
+		for token, weight := range tokens {
+			rows = append(rows, models.ItemSearchToken{ItemID: itemId, Token: token, Weight: weight})
+		}
+		return tx.CreateInBatches(&rows, 500).Error
+	})
+}
+
+// DeleteTokens implements ISearchRepository.
+func (r *SearchRepository) DeleteTokens(itemId uint) error {
+	return r.db.Where("item_id = ?", itemId).Delete(&models.ItemSearchToken{}).Error
+}
+
+// Search implements ISearchRepository.
+// クエリの全トークンを含むアイテムだけを対象に、重みの合計が大きい順に返します
+// インデックスに残っている非公開のアイテムが件数に含まれないように、アイテムと結合してステータスで絞り込みます
+func (r *SearchRepository) Search(tokens []string, statuses []string, limit int, offset int) ([]SearchHit, int64, error) {
+	matched := r.db.Model(&models.ItemSearchToken{}).
+		Select("item_search_tokens.item_id, SUM(item_search_tokens.weight) AS score").
+		Joins("JOIN items ON items.id = item_search_tokens.item_id AND items.status IN ? AND items.deleted_at IS NULL", statuses).
+		Where("item_search_tokens.token IN ?", tokens).
+		Group("item_search_tokens.item_id").
+		Having("COUNT(*) = ?", len(tokens))
+
+	var total int64
+	if err := r.db.Table("(?) AS hits", matched).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var hits []SearchHit
+	result := r.db.Table("(?) AS hits", matched).
+		Order("score DESC").Order("item_id DESC").
+		Limit(limit).Offset(offset).
+		Scan(&hits)
+	if result.Error != nil {
+		return nil, 0, result.Error
+	}
+	return hits, total, nil
+}
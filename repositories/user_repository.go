@@ -0,0 +1,50 @@
+package repositories
+
+import (
+	"errors"
+	"gin-fleamarket/models"
+
+	"gorm.io/gorm"
+)
+
+type IUserRepository interface {
+	FindById(userId uint) (*models.User, error)
+}
+
+type UserMemoryRepository struct {
+	users []models.User
+}
+
+func NewUserMemoryRepository(users []models.User) IUserRepository {
+	return &UserMemoryRepository{users: users}
+}
+
+func (r *UserMemoryRepository) FindById(userId uint) (*models.User, error) {
+	for _, v := range r.users {
+		if v.ID == userId {
+			return &v, nil
+		}
+	}
+	return nil, errors.New("User not found")
+}
+
+type UserRepository struct {
+	db *gorm.DB
+}
+
+func NewUserRepository(db *gorm.DB) IUserRepository {
+	return &UserRepository{db: db}
+}
+
+// FindById implements IUserRepository.
+func (r *UserRepository) FindById(userId uint) (*models.User, error) {
+	var user models.User
+	result := r.db.First(&user, "id = ?", userId)
+	if result.Error != nil {
+		if result.Error.Error() == "record not found" {
+			return nil, errors.New("User not found")
+		}
+		return nil, result.Error
+	}
+	return &user, nil
+}
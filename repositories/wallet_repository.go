@@ -0,0 +1,145 @@
+package repositories
+
+import (
+	"errors"
+	"gin-fleamarket/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IWalletRepository interface {
+	Transaction(fn func(tx IWalletRepository) error) error
+	// LockUser はユーザーの残高を変更する操作を直列化するためにユーザーの行をロックします
+	LockUser(userId uint) error
+	CreateEntries(entries []models.LedgerEntry) error
+	// Balance は勘定の残高を返します。userId が nil の場合はプラットフォームの勘定です
+	Balance(account string, userId *uint) (int64, error)
+	FindEntries(userId uint, limit int, offset int) (*[]models.LedgerEntry, int64, error)
+	CreatePayout(newPayout models.Payout) (*models.Payout, error)
+	LockPayout(payoutId uint) (*models.Payout, error)
+	UpdatePayout(updatePayout models.Payout) (*models.Payout, error)
+	// FindPayouts は userId が nil の場合は全ユーザー、status が空の場合は全ステータスの出金申請を返します
+	FindPayouts(userId *uint, status string, limit int, offset int) (*[]models.Payout, int64, error)
+}
+
+type WalletRepository struct {
+	db *gorm.DB
+}
+
+func NewWalletRepository(db *gorm.DB) IWalletRepository {
+	return &WalletRepository{db: db}
+}
+
+// Transaction implements IWalletRepository.
+func (r *WalletRepository) Transaction(fn func(tx IWalletRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&WalletRepository{db: tx})
+	})
+}
+
+// LockUser implements IWalletRepository.
+func (r *WalletRepository) LockUser(userId uint) error {
+	var user models.User
+	result := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, "id = ?", userId)
+	if result.Error != nil {
+		if result.Error.Error() == "record not found" {
+			return errors.New("User not found")
+		}
+		return result.Error
+	}
+	return nil
+}
+
+// CreateEntries implements IWalletRepository.
+func (r *WalletRepository) CreateEntries(entries []models.LedgerEntry) error {
+	return r.db.Create(&entries).Error
+}
+
+// Balance implements IWalletRepository.
+func (r *WalletRepository) Balance(account string, userId *uint) (int64, error) {
+	query := r.db.Model(&models.LedgerEntry{}).Where("account = ?", account)
+	if userId != nil {
+		query = query.Where("user_id = ?", *userId)
+	} else {
+		query = query.Where("user_id IS NULL")
+	}
+
+	var balance int64
+	result := query.Select("COALESCE(SUM(amount), 0)").Scan(&balance)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return balance, nil
+}
+
+// FindEntries implements IWalletRepository.
+func (r *WalletRepository) FindEntries(userId uint, limit int, offset int) (*[]models.LedgerEntry, int64, error) {
+	query := r.db.Model(&models.LedgerEntry{}).Where("user_id = ?", userId)
+
+	var total int64
+	if result := query.Session(&gorm.Session{}).Count(&total); result.Error != nil {
+		return nil, 0, result.Error
+	}
+
+	var entries []models.LedgerEntry
+	result := query.Order("id DESC").Limit(limit).Offset(offset).Find(&entries)
+	if result.Error != nil {
+		return nil, 0, result.Error
+	}
+	return &entries, total, nil
+}
+
+// CreatePayout implements IWalletRepository.
+func (r *WalletRepository) CreatePayout(newPayout models.Payout) (*models.Payout, error) {
+	result := r.db.Create(&newPayout)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &newPayout, nil
+}
+
+// LockPayout implements IWalletRepository.
+func (r *WalletRepository) LockPayout(payoutId uint) (*models.Payout, error) {
+	var payout models.Payout
+	result := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&payout, "id = ?", payoutId)
+	if result.Error != nil {
+		if result.Error.Error() == "record not found" {
+			return nil, errors.New("Payout not found")
+		}
+		return nil, result.Error
+	}
+	return &payout, nil
+}
+
+// UpdatePayout implements IWalletRepository.
+func (r *WalletRepository) UpdatePayout(updatePayout models.Payout) (*models.Payout, error) {
+	result := r.db.Save(&updatePayout)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &updatePayout, nil
+}
+
+// FindPayouts implements IWalletRepository.
+func (r *WalletRepository) FindPayouts(userId *uint, status string, limit int, offset int) (*[]models.Payout, int64, error) {
+	query := r.db.Model(&models.Payout{})
+	if userId != nil {
+		query = query.Where("user_id = ?", *userId)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if result := query.Session(&gorm.Session{}).Count(&total); result.Error != nil {
+		return nil, 0, result.Error
+	}
+
+	var payouts []models.Payout
+	result := query.Order("id DESC").Limit(limit).Offset(offset).Find(&payouts)
+	if result.Error != nil {
+		return nil, 0, result.Error
+	}
+	return &payouts, total, nil
+}
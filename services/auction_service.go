@@ -0,0 +1,195 @@
+package services
+
+import (
+	"errors"
+	"gin-fleamarket/dto"
+	"gin-fleamarket/models"
+	"gin-fleamarket/repositories"
+	"log"
+	"time"
+)
+
+const (
+	minBidIncrement     = 10
+	minAuctionDuration  = time.Hour
+	maxAuctionDuration  = 30 * 24 * time.Hour
+	defaultBidPageLimit = 20
+)
+
+type IAuctionService interface {
+	PlaceBid(itemId uint, bidderId uint, input dto.PlaceBidInput) (*dto.BidOutput, error)
+	FindBids(itemId uint, query dto.PageQuery) (*dto.BidPage, error)
+	CloseEnded(now time.Time) (int, error)
+	Run(interval time.Duration)
+}
+
+// AuctionService はオークション形式の出品への入札と終了処理を行います
+// 終了間際の入札があった場合は、終了時刻を入札時刻から extensionWindow 後まで延長します
+type AuctionService struct {
+	repository      repositories.IOrderRepository
+	extensionWindow time.Duration
+}
+
+func NewAuctionService(repository repositories.IOrderRepository, extensionWindow time.Duration) IAuctionService {
+	return &AuctionService{repository: repository, extensionWindow: extensionWindow}
+}
+
+// PlaceBid はオークションの行をロックしてから入札額を確認するため、同時に入札されても最高入札額が巻き戻ることはありません
+func (s *AuctionService) PlaceBid(itemId uint, bidderId uint, input dto.PlaceBidInput) (*dto.BidOutput, error) {
+	var output *dto.BidOutput
+	err := s.repository.Transaction(func(tx repositories.IOrderRepository) error {
+		auction, err := tx.Auctions().LockByItem(itemId)
+		if err != nil {
+			return err
+		}
+		item, err := tx.LockItem(itemId)
+		if err != nil {
+			return err
+		}
+		if item.Status == models.ItemStatusDraft || item.Status == models.ItemStatusCancelled {
+			return errors.New("Item not found")
+		}
+		if item.UserID == bidderId {
+			return errors.New("Cannot bid on own item")
+		}
+
+		now := time.Now()
+		if auction.Status != models.AuctionStatusOpen || !now.Before(auction.EndsAt) {
+			return errors.New("Auction ended")
+		}
+		if input.Amount < minimumBid(*auction) {
+			return errors.New("Bid too low")
+		}
+
+		bid, err := tx.Auctions().CreateBid(models.Bid{AuctionID: auction.ID, BidderID: bidderId, Amount: input.Amount})
+		if err != nil {
+			return err
+		}
+
+		expectedBidCount := auction.BidCount
+		auction.CurrentPrice = input.Amount
+		auction.HighestBidderID = &bidderId
+		auction.BidCount++
+		if auction.EndsAt.Sub(now) < s.extensionWindow {
+			auction.EndsAt = now.Add(s.extensionWindow)
+		}
+		updatedAuction, err := tx.Auctions().Update(*auction, expectedBidCount)
+		if err != nil {
+			if err.Error() == "Version conflict" {
+				return errors.New("Bid too low")
+			}
+			return err
+		}
+		output = &dto.BidOutput{Bid: *bid, Auction: *updatedAuction}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+func (s *AuctionService) FindBids(itemId uint, query dto.PageQuery) (*dto.BidPage, error) {
+	auction, err := s.repository.Auctions().FindByItem(itemId)
+	if err != nil {
+		return nil, err
+	}
+	limit := query.Limit
+	if limit == 0 {
+		limit = defaultBidPageLimit
+	}
+	bids, total, err := s.repository.Auctions().FindBids(auction.ID, limit, query.Offset)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.BidPage{Bids: *bids, Total: total}, nil
+}
+
+// CloseEnded は終了時刻を過ぎたオークションを締め切ります
+// 最低落札価格以上の入札があれば最高入札者を落札者として支払い待ちの注文を作り、なければ出品を終了します
+func (s *AuctionService) CloseEnded(now time.Time) (int, error) {
+	itemIds, err := s.repository.Auctions().FindEndedItemIds(now)
+	if err != nil {
+		return 0, err
+	}
+
+	closed := 0
+	for _, itemId := range itemIds {
+		if err := s.close(itemId, now); err != nil {
+			return closed, err
+		}
+		closed++
+	}
+	return closed, nil
+}
+
+func (s *AuctionService) close(itemId uint, now time.Time) error {
+	return s.repository.Transaction(func(tx repositories.IOrderRepository) error {
+		auction, err := tx.Auctions().LockByItem(itemId)
+		if err != nil {
+			return err
+		}
+		// 一覧を取得した後に入札で延長されたか、既に締め切られている
+		if auction.Status != models.AuctionStatusOpen || now.Before(auction.EndsAt) {
+			return nil
+		}
+
+		item, err := tx.LockItem(itemId)
+		if err != nil && err.Error() != "Item not found" {
+			return err
+		}
+		if item != nil && item.Status == models.ItemStatusListed {
+			if auction.HighestBidderID != nil && auction.CurrentPrice >= auction.ReservePrice {
+				if _, err := updateItemStatus(tx, *item, models.ItemStatusSold); err != nil {
+					return err
+				}
+				order, err := tx.Create(models.Order{
+					ItemID:   item.ID,
+					BuyerID:  *auction.HighestBidderID,
+					SellerID: item.UserID,
+					Price:    auction.CurrentPrice,
+					Status:   models.OrderStatusPendingPayment,
+				})
+				if err != nil {
+					return err
+				}
+				if err := recordOrderEvent(tx, *order, nil, models.OrderActionAuctionWon, "", ""); err != nil {
+					return err
+				}
+				auction.WinnerID = auction.HighestBidderID
+				auction.OrderID = &order.ID
+			} else if _, err := updateItemStatus(tx, *item, models.ItemStatusCancelled); err != nil {
+				return err
+			}
+		}
+
+		auction.Status = models.AuctionStatusClosed
+		auction.ClosedAt = &now
+		_, err = tx.Auctions().Update(*auction, auction.BidCount)
+		return err
+	})
+}
+
+// Run は interval ごとに CloseEnded を実行し続けます。goroutine で呼び出してください
+func (s *AuctionService) Run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		closed, err := s.CloseEnded(now)
+		if err != nil {
+			log.Printf("Failed to close auctions: %v", err)
+			continue
+		}
+		if closed > 0 {
+			log.Printf("Closed %d auctions", closed)
+		}
+	}
+}
+
+// minimumBid は次に受け付ける最低入札額を返します。最初の入札は開始価格から受け付けます
+func minimumBid(auction models.Auction) uint {
+	if auction.BidCount == 0 {
+		return auction.StartPrice
+	}
+	return auction.CurrentPrice + minBidIncrement
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"gin-fleamarket/dto"
+	"gin-fleamarket/infra"
+	"gin-fleamarket/models"
+	"gin-fleamarket/repositories"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gorm.io/gorm"
+	"gotest.tools/v3/assert"
+)
+
+func setupAuctionServiceTest() (IAuctionService, *gorm.DB) {
+	// テスト環境の読み込み
+	if err := godotenv.Load("../.env.test"); err != nil {
+		os.Setenv("ENV", "test")
+	}
+
+	db := infra.SetupDB()
+	db.AutoMigrate(&models.Item{}, &models.Auction{}, &models.Bid{}, &models.Order{}, &models.OrderEvent{}, &models.ItemSearchToken{})
+	return NewAuctionService(repositories.NewOrderRepository(db), 5*time.Minute), db
+}
+
+func createAuctionItem(db *gorm.DB, reservePrice uint, endsAt time.Time) models.Item {
+	item := models.Item{
+		Name:        "オークション",
+		Price:       1000,
+		Status:      models.ItemStatusListed,
+		UserID:      1,
+		ListingType: models.ListingTypeAuction,
+		Auction:     &models.Auction{StartPrice: 1000, ReservePrice: reservePrice, EndsAt: endsAt, Status: models.AuctionStatusOpen},
+	}
+	db.Create(&item)
+	return item
+}
+
+func TestCloseEndedAuctions(t *testing.T) {
+	auctionService, db := setupAuctionServiceTest()
+
+	endsAt := time.Now().Add(time.Hour)
+	won := createAuctionItem(db, 0, endsAt)
+	belowReserve := createAuctionItem(db, 5000, endsAt)
+	noBids := createAuctionItem(db, 0, endsAt)
+	searchRepository := repositories.NewSearchRepository(db)
+	for _, item := range []models.Item{won, belowReserve, noBids} {
+		searchRepository.ReplaceTokens(item.ID, map[string]uint{"オー": 3})
+	}
+
+	_, err := auctionService.PlaceBid(won.ID, 2, dto.PlaceBidInput{Amount: 1200})
+	assert.NilError(t, err)
+	_, err = auctionService.PlaceBid(belowReserve.ID, 2, dto.PlaceBidInput{Amount: 1200})
+	assert.NilError(t, err)
+
+	// テストケース1: 終了時刻前は締め切られない
+	closed, err := auctionService.CloseEnded(time.Now())
+	assert.NilError(t, err)
+	assert.Equal(t, 0, closed)
+
+	// テストケース2: 終了時刻を過ぎると締め切られる
+	closed, err = auctionService.CloseEnded(endsAt.Add(time.Second))
+	assert.NilError(t, err)
+	assert.Equal(t, 3, closed)
+
+	// 最低落札価格以上の入札があれば落札者の注文が作られる
+	var auction models.Auction
+	db.First(&auction, "item_id = ?", won.ID)
+	assert.Equal(t, models.AuctionStatusClosed, auction.Status)
+	assert.Equal(t, uint(2), *auction.WinnerID)
+
+	var order models.Order
+	db.First(&order, *auction.OrderID)
+	assert.Equal(t, uint(2), order.BuyerID)
+	assert.Equal(t, uint(1200), order.Price)
+	assert.Equal(t, models.OrderStatusPendingPayment, order.Status)
+
+	var item models.Item
+	db.First(&item, won.ID)
+	assert.Equal(t, models.ItemStatusSold, item.Status)
+
+	// 最低落札価格に届かない場合や入札がない場合は出品が終了し、検索インデックスからも削除される
+	for _, itemId := range []uint{belowReserve.ID, noBids.ID} {
+		var auction models.Auction
+		db.First(&auction, "item_id = ?", itemId)
+		assert.Equal(t, models.AuctionStatusClosed, auction.Status)
+		assert.Assert(t, auction.WinnerID == nil)
+
+		var item models.Item
+		db.First(&item, itemId)
+		assert.Equal(t, models.ItemStatusCancelled, item.Status)
+
+		var tokenCount int64
+		db.Model(&models.ItemSearchToken{}).Where("item_id = ?", itemId).Count(&tokenCount)
+		assert.Equal(t, int64(0), tokenCount)
+	}
+
+	// テストケース3: 締め切った後は入札できない
+	_, err = auctionService.PlaceBid(won.ID, 3, dto.PlaceBidInput{Amount: 2000})
+	assert.Error(t, err, "Auction ended")
+}
+
+func TestBidExtendsAuction(t *testing.T) {
+	auctionService, db := setupAuctionServiceTest()
+
+	// 終了間際の入札で終了時刻が延長される
+	endsAt := time.Now().Add(2 * time.Minute)
+	item := createAuctionItem(db, 0, endsAt)
+
+	output, err := auctionService.PlaceBid(item.ID, 2, dto.PlaceBidInput{Amount: 1000})
+	assert.NilError(t, err)
+	assert.Assert(t, output.Auction.EndsAt.After(endsAt.Add(2*time.Minute)))
+
+	// 最低入札単位に満たない入札は受け付けない
+	_, err = auctionService.PlaceBid(item.ID, 3, dto.PlaceBidInput{Amount: 1005})
+	assert.Error(t, err, "Bid too low")
+	_, err = auctionService.PlaceBid(item.ID, 1, dto.PlaceBidInput{Amount: 2000})
+	assert.Error(t, err, "Cannot bid on own item")
+}
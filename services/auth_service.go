@@ -1,28 +1,59 @@
 package services
 
 import (
-	"fmt"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"gin-fleamarket/dto"
+	"gin-fleamarket/mail"
 	"gin-fleamarket/models"
 	"gin-fleamarket/repositories"
 	"github.com/golang-jwt/jwt/v5"
+	"log"
+	"net/url"
 	"os"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
+const (
+	accessTokenTTL       = time.Hour
+	emailVerificationTTL = 24 * time.Hour
+	passwordResetTTL     = 30 * time.Minute
+	// userTokenCooldown の間は、同じ用途のメールを送り直さない
+	userTokenCooldown = 5 * time.Minute
+)
+
 type IAuthService interface {
 	Signup(email string, password string) error
-	Login(email string, password string) (*string, error)
+	VerifyEmail(token string) error
+	ResendVerification(email string) error
+	ForgotPassword(email string) error
+	ResetPassword(token string, password string) error
+	ChangePassword(userId uint, currentPassword string, newPassword string) error
+	Login(email string, password string) (*dto.TokenOutput, error)
+	Refresh(refreshToken string) (*dto.TokenOutput, error)
+	Logout(tokenString string, refreshToken string) error
+	LogoutAll(userId uint) error
 	GetUserFromToken(tokenString string) (*models.User, error)
+	JWKS() dto.JWKSetOutput
 }
 
+// AuthService はログイン時にアクセストークンとリフレッシュトークンを発行します
+// リフレッシュトークンは一度しか使えず、使用済みのトークンが再び使われた場合は盗まれたものとみなして同じファミリーのトークンをすべて失効させます
+// アクセストークンはログアウトで jti ごとに、全端末からのログアウトでユーザーのトークンバージョンごとに失効します
+// 登録したユーザーにはメールアドレスの確認メールを送り、確認が済むまでは出品できません
+// パスワードを再設定・変更すると、発行済みのアクセストークンとリフレッシュトークンはすべて失効します
 type AuthService struct {
-	repository repositories.IAuthRepository
+	repository      repositories.IAuthRepository
+	mailer          mail.IMailer
+	keys            *TokenKeys
+	refreshTokenTTL time.Duration
 }
 
-func NewAuthService(repository repositories.IAuthRepository) IAuthService {
-	return &AuthService{repository: repository}
+func NewAuthService(repository repositories.IAuthRepository, mailer mail.IMailer, keys *TokenKeys, refreshTokenTTL time.Duration) IAuthService {
+	return &AuthService{repository: repository, mailer: mailer, keys: keys, refreshTokenTTL: refreshTokenTTL}
 }
 
 func (s *AuthService) Signup(email string, password string) error {
@@ -39,10 +70,137 @@ func (s *AuthService) Signup(email string, password string) error {
 		Email:    email,
 		Password: string(hashedPassword),
 	}
-	return s.repository.CreateUser(user)
+	createdUser, err := s.repository.CreateUser(user)
+	if err != nil {
+		return err
+	}
+	// 確認メールを送れなくても登録は取り消さない。確認メールは送り直せる
+	if err := s.sendVerification(*createdUser); err != nil {
+		log.Printf("Failed to send verification mail: %v", err)
+	}
+	return nil
+}
+
+// VerifyEmail は確認メールのトークンを使ってメールアドレスを確認済みにします
+func (s *AuthService) VerifyEmail(token string) error {
+	now := time.Now()
+	return s.repository.Transaction(func(tx repositories.IAuthRepository) error {
+		userToken, err := useUserToken(tx, token, models.UserTokenPurposeEmailVerification, now)
+		if err != nil {
+			return err
+		}
+		return tx.VerifyEmail(userToken.UserID, now)
+	})
+}
+
+// ResendVerification は確認メールを送り直します
+// メールアドレスが登録済みかどうかを知られないように、未登録や確認済みの場合もエラーにしません
+// 直前に送った確認メールのトークンが未使用のまま残っている間は送り直しません
+func (s *AuthService) ResendVerification(email string) error {
+	user, err := s.repository.FindUser(email)
+	if err != nil {
+		if err.Error() == "User not found" {
+			return nil
+		}
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+	recent, err := s.repository.HasRecentUserToken(user.ID, models.UserTokenPurposeEmailVerification, time.Now().Add(-userTokenCooldown))
+	if err != nil || recent {
+		return err
+	}
+	return s.sendVerification(*user)
 }
 
-func (s *AuthService) Login(email string, password string) (*string, error) {
+func (s *AuthService) sendVerification(user models.User) error {
+	token, err := issueUserToken(s.repository, user.ID, models.UserTokenPurposeEmailVerification, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(mail.Message{
+		To:      user.Email,
+		Subject: "メールアドレスの確認",
+		Body:    "以下のURLを開いて、メールアドレスの確認を完了してください。\n" + appURL("/auth/verify?token="+url.QueryEscape(token)) + "\n\nこのURLの有効期限は24時間です。",
+	})
+}
+
+// ForgotPassword はパスワード再設定用のトークンをメールで送ります
+// ResendVerification と同様に、未登録のメールアドレスでもエラーにせず、直前に送ったトークンが未使用のまま残っている間は送り直しません
+func (s *AuthService) ForgotPassword(email string) error {
+	user, err := s.repository.FindUser(email)
+	if err != nil {
+		if err.Error() == "User not found" {
+			return nil
+		}
+		return err
+	}
+	recent, err := s.repository.HasRecentUserToken(user.ID, models.UserTokenPurposePasswordReset, time.Now().Add(-userTokenCooldown))
+	if err != nil || recent {
+		return err
+	}
+	token, err := issueUserToken(s.repository, user.ID, models.UserTokenPurposePasswordReset, passwordResetTTL)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(mail.Message{
+		To:      user.Email,
+		Subject: "パスワードの再設定",
+		Body:    "以下のトークンを使って、パスワードを再設定してください。\n" + token + "\n\nこのトークンの有効期限は30分です。心当たりがない場合は、このメールを破棄してください。",
+	})
+}
+
+// ResetPassword はパスワード再設定用のトークンを使ってパスワードを設定し直します
+// 再設定メールを受け取れたことでメールアドレスも確認できたものとし、未使用の再設定用トークンはすべて使えなくします
+func (s *AuthService) ResetPassword(token string, password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	return s.repository.Transaction(func(tx repositories.IAuthRepository) error {
+		userToken, err := useUserToken(tx, token, models.UserTokenPurposePasswordReset, now)
+		if err != nil {
+			return err
+		}
+		if err := tx.ExpireUserTokens(userToken.UserID, models.UserTokenPurposePasswordReset, now); err != nil {
+			return err
+		}
+		if err := tx.VerifyEmail(userToken.UserID, now); err != nil {
+			return err
+		}
+		return updatePassword(tx, userToken.UserID, string(hashedPassword), now)
+	})
+}
+
+// ChangePassword は現在のパスワードを確認してからパスワードを変更します
+func (s *AuthService) ChangePassword(userId uint, currentPassword string, newPassword string) error {
+	user, err := s.repository.FindUserById(userId)
+	if err != nil {
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(currentPassword)); err != nil {
+		return errors.New("Invalid password")
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	return s.repository.Transaction(func(tx repositories.IAuthRepository) error {
+		return updatePassword(tx, userId, string(hashedPassword), time.Now())
+	})
+}
+
+// updatePassword はパスワードを更新し、ユーザーに発行済みのトークンをすべて失効させます
+func updatePassword(repository repositories.IAuthRepository, userId uint, hashedPassword string, now time.Time) error {
+	if err := repository.UpdatePassword(userId, hashedPassword); err != nil {
+		return err
+	}
+	return revokeAllTokens(repository, userId, now)
+}
+
+func (s *AuthService) Login(email string, password string) (*dto.TokenOutput, error) {
 	foundUser, err := s.repository.FindUser(email)
 	if err != nil {
 		return nil, err
@@ -53,48 +211,248 @@ func (s *AuthService) Login(email string, password string) (*string, error) {
 		return nil, err
 	}
 
-	token, err := CreateToken(foundUser.ID, foundUser.Email)
+	// ログインのたびに新しいファミリーを始める
+	familyId, err := randomHex(16)
 	if err != nil {
 		return nil, err
 	}
+	return s.issueTokens(s.repository, *foundUser, familyId, time.Now())
+}
 
-	return token, nil
+// Refresh はリフレッシュトークンを使用済みにし、同じファミリーの新しいリフレッシュトークンとアクセストークンを発行します
+func (s *AuthService) Refresh(refreshToken string) (*dto.TokenOutput, error) {
+	now := time.Now()
+	var output *dto.TokenOutput
+	var reusedFamilyId string
+	err := s.repository.Transaction(func(tx repositories.IAuthRepository) error {
+		token, err := tx.FindRefreshToken(hashToken(refreshToken))
+		if err != nil {
+			return err
+		}
+		if token.RevokedAt != nil || !now.Before(token.ExpiresAt) {
+			return errors.New("Invalid refresh token")
+		}
+		marked, err := tx.MarkRefreshTokenUsed(token.ID, now)
+		if err != nil {
+			return err
+		}
+		if !marked {
+			reusedFamilyId = token.FamilyID
+			return nil
+		}
+
+		user, err := tx.FindUserById(token.UserID)
+		if err != nil {
+			return err
+		}
+		output, err = s.issueTokens(tx, *user, token.FamilyID, now)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	// 失効はエラーを返す前に確定させたいので、トランザクションの外で行う
+	if reusedFamilyId != "" {
+		if err := s.repository.RevokeRefreshTokenFamily(reusedFamilyId, now); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("Refresh token reused")
+	}
+	return output, nil
+}
+
+func (s *AuthService) issueTokens(repository repositories.IAuthRepository, user models.User, familyId string, now time.Time) (*dto.TokenOutput, error) {
+	accessToken, err := createToken(s.keys, user.ID, user.Email, user.TokenVersion)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := randomHex(32)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := repository.CreateRefreshToken(models.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyId,
+		TokenHash: hashToken(refreshToken),
+		ExpiresAt: now.Add(s.refreshTokenTTL),
+	}); err != nil {
+		return nil, err
+	}
+	return &dto.TokenOutput{
+		Token:        *accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(accessTokenTTL.Seconds()),
+	}, nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Logout はアクセストークンを失効させます。refreshToken を指定した場合は、そのリフレッシュトークンのファミリーも失効させます
+func (s *AuthService) Logout(tokenString string, refreshToken string) error {
+	claims, err := parseToken(s.keys, tokenString)
+	if err != nil {
+		return err
+	}
+	userId, jti, expiresAt := tokenClaims(claims)
+	if jti == "" {
+		return errors.New("Invalid token")
+	}
+	now := time.Now()
+	return s.repository.Transaction(func(tx repositories.IAuthRepository) error {
+		if err := tx.RevokeToken(models.RevokedToken{JTI: jti, UserID: userId, ExpiresAt: expiresAt}); err != nil {
+			return err
+		}
+		if refreshToken == "" {
+			return nil
+		}
+		token, err := tx.FindRefreshToken(hashToken(refreshToken))
+		if err != nil {
+			return err
+		}
+		if token.UserID != userId {
+			return errors.New("Invalid refresh token")
+		}
+		return tx.RevokeRefreshTokenFamily(token.FamilyID, now)
+	})
+}
+
+// LogoutAll はユーザーに発行済みのアクセストークンとリフレッシュトークンをすべて失効させます
+func (s *AuthService) LogoutAll(userId uint) error {
+	return s.repository.Transaction(func(tx repositories.IAuthRepository) error {
+		return revokeAllTokens(tx, userId, time.Now())
+	})
 }
 
-func CreateToken(userId uint, email string) (*string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+func revokeAllTokens(repository repositories.IAuthRepository, userId uint, now time.Time) error {
+	if err := repository.IncrementTokenVersion(userId); err != nil {
+		return err
+	}
+	return repository.RevokeUserRefreshTokens(userId, now)
+}
+
+func createToken(keys *TokenKeys, userId uint, email string, version uint) (*string, error) {
+	jti, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	tokenString, err := keys.sign(jwt.MapClaims{
 		"sub":   userId,
 		"email": email,
-		"exp":   time.Now().Add(time.Hour).Unix(),
+		"jti":   jti,
+		"ver":   version,
+		"exp":   time.Now().Add(accessTokenTTL).Unix(),
 	})
-
-	tokenString, err := token.SignedString([]byte(os.Getenv("SECRET_KEY")))
 	if err != nil {
 		return nil, err
 	}
 	return &tokenString, nil
 }
 
+func parseToken(keys *TokenKeys, tokenString string) (jwt.MapClaims, error) {
+	token, err := keys.parse(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errors.New("Invalid token")
+	}
+	if exp, ok := claims["exp"].(float64); !ok || float64(time.Now().Unix()) > exp {
+		return nil, jwt.ErrTokenExpired
+	}
+	return claims, nil
+}
+
+// tokenClaims はクレームからユーザーID、jti、有効期限を取り出します。含まれていない値はゼロ値になります
+func tokenClaims(claims jwt.MapClaims) (userId uint, jti string, expiresAt time.Time) {
+	if sub, ok := claims["sub"].(float64); ok {
+		userId = uint(sub)
+	}
+	jti, _ = claims["jti"].(string)
+	if exp, ok := claims["exp"].(float64); ok {
+		expiresAt = time.Unix(int64(exp), 0)
+	}
+	return userId, jti, expiresAt
+}
+
 func (s *AuthService) GetUserFromToken(tokenString string) (*models.User, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(os.Getenv("SECRET_KEY")), nil
-	})
+	claims, err := parseToken(s.keys, tokenString)
 	if err != nil {
 		return nil, err
 	}
 
-	var user *models.User
-	if claims, ok := token.Claims.(jwt.MapClaims); ok {
-		if float64(time.Now().Unix()) > claims["exp"].(float64) {
-			return nil, jwt.ErrTokenExpired
-		}
-		user, err = s.repository.FindUser(claims["email"].(string))
+	if _, jti, _ := tokenClaims(claims); jti != "" {
+		revoked, err := s.repository.IsTokenRevoked(jti)
 		if err != nil {
 			return nil, err
 		}
+		if revoked {
+			return nil, errors.New("Token revoked")
+		}
+	}
+
+	email, _ := claims["email"].(string)
+	user, err := s.repository.FindUser(email)
+	if err != nil {
+		return nil, err
+	}
+	// ver クレームのないトークンはバージョン 0 として扱う
+	version, _ := claims["ver"].(float64)
+	if uint(version) != user.TokenVersion {
+		return nil, errors.New("Token revoked")
 	}
 	return user, nil
 }
+
+// JWKS は他のサービスがアクセストークンを検証するための公開鍵を返します
+func (s *AuthService) JWKS() dto.JWKSetOutput {
+	return s.keys.jwks()
+}
+
+// issueUserToken はメールで送るための一度だけ使えるトークンを発行します
+func issueUserToken(repository repositories.IAuthRepository, userId uint, purpose string, ttl time.Duration) (string, error) {
+	token, err := randomHex(32)
+	if err != nil {
+		return "", err
+	}
+	if _, err := repository.CreateUserToken(models.UserToken{
+		UserID:    userId,
+		Purpose:   purpose,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+	}); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// useUserToken はトークンを使用済みにします。不明、期限切れ、使用済みのトークンは "Invalid token" になります
+func useUserToken(repository repositories.IAuthRepository, token string, purpose string, now time.Time) (*models.UserToken, error) {
+	userToken, err := repository.FindUserToken(hashToken(token), purpose)
+	if err != nil {
+		return nil, err
+	}
+	if !now.Before(userToken.ExpiresAt) {
+		return nil, errors.New("Invalid token")
+	}
+	marked, err := repository.MarkUserTokenUsed(userToken.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !marked {
+		return nil, errors.New("Invalid token")
+	}
+	return userToken, nil
+}
+
+// appURL はメールに載せるURLを APP_BASE_URL から組み立てます
+func appURL(path string) string {
+	baseURL := os.Getenv("APP_BASE_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:8080"
+	}
+	return baseURL + path
+}
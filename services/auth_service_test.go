@@ -2,9 +2,11 @@ package services
 
 import (
 	"gin-fleamarket/infra"
+	"gin-fleamarket/mail"
 	"gin-fleamarket/models"
 	"gin-fleamarket/repositories"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -26,14 +28,15 @@ func setupAuthServiceTest() (IAuthService, *gorm.DB) {
 
 	// データベースのセットアップ
 	db := infra.SetupDB()
-	db.AutoMigrate(&models.User{})
+	db.AutoMigrate(&models.User{}, &models.RefreshToken{}, &models.RevokedToken{}, &models.UserToken{}, &models.OutboxMail{})
 
 	// 既存のユーザーをクリア
 	db.Exec("DELETE FROM users")
 
 	// リポジトリとサービスのセットアップ
+	tokenKeys, _ := SetupTokenKeys()
 	authRepository := repositories.NewAuthRepository(db)
-	authService := NewAuthService(authRepository)
+	authService := NewAuthService(authRepository, mail.NewOutboxMailer(db), tokenKeys, time.Hour)
 
 	return authService, db
 }
@@ -81,6 +84,9 @@ func TestLogin(t *testing.T) {
 	token, err := authService.Login("test@example.com", "password123")
 	assert.NilError(t, err)
 	assert.Assert(t, token != nil)
+	assert.Assert(t, token.Token != "")
+	assert.Assert(t, token.RefreshToken != "")
+	assert.Equal(t, int64(3600), token.ExpiresIn)
 
 	// テストケース2: 存在しないユーザーでのログイン
 	token, err = authService.Login("nonexistent@example.com", "password123")
@@ -93,6 +99,223 @@ func TestLogin(t *testing.T) {
 	assert.Assert(t, token == nil)
 }
 
+func TestRefresh(t *testing.T) {
+	authService, db := setupAuthServiceTest()
+	defer db.Exec("DELETE FROM users")
+	defer db.Exec("DELETE FROM refresh_tokens")
+
+	err := authService.Signup("test@example.com", "password123")
+	assert.NilError(t, err)
+	login, err := authService.Login("test@example.com", "password123")
+	assert.NilError(t, err)
+
+	// リフレッシュトークンはハッシュだけが保存される
+	var stored models.RefreshToken
+	db.First(&stored)
+	assert.Assert(t, stored.TokenHash != login.RefreshToken)
+
+	// テストケース1: 再発行すると新しいリフレッシュトークンに置き換わる
+	refreshed, err := authService.Refresh(login.RefreshToken)
+	assert.NilError(t, err)
+	assert.Assert(t, refreshed.RefreshToken != login.RefreshToken)
+	user, err := authService.GetUserFromToken(refreshed.Token)
+	assert.NilError(t, err)
+	assert.Equal(t, "test@example.com", user.Email)
+
+	// テストケース2: 使用済みのトークンを使うとファミリーごと失効する
+	_, err = authService.Refresh(login.RefreshToken)
+	assert.Error(t, err, "Refresh token reused")
+	_, err = authService.Refresh(refreshed.RefreshToken)
+	assert.Error(t, err, "Invalid refresh token")
+
+	// テストケース3: 他のログインのトークンは影響を受けない
+	other, err := authService.Login("test@example.com", "password123")
+	assert.NilError(t, err)
+	_, err = authService.Refresh(other.RefreshToken)
+	assert.NilError(t, err)
+
+	// テストケース4: 不明なトークンと期限切れのトークン
+	_, err = authService.Refresh("unknown")
+	assert.Error(t, err, "Invalid refresh token")
+
+	tokenKeys, err := SetupTokenKeys()
+	assert.NilError(t, err)
+	expired, err := NewAuthService(repositories.NewAuthRepository(db), mail.NewOutboxMailer(db), tokenKeys, -time.Second).Login("test@example.com", "password123")
+	assert.NilError(t, err)
+	_, err = authService.Refresh(expired.RefreshToken)
+	assert.Error(t, err, "Invalid refresh token")
+}
+
+func TestLogout(t *testing.T) {
+	authService, db := setupAuthServiceTest()
+	defer db.Exec("DELETE FROM users")
+	defer db.Exec("DELETE FROM refresh_tokens")
+	defer db.Exec("DELETE FROM revoked_tokens")
+
+	err := authService.Signup("test@example.com", "password123")
+	assert.NilError(t, err)
+	first, err := authService.Login("test@example.com", "password123")
+	assert.NilError(t, err)
+	second, err := authService.Login("test@example.com", "password123")
+	assert.NilError(t, err)
+
+	// テストケース1: ログアウトしたトークンだけが使えなくなる
+	err = authService.Logout(first.Token, first.RefreshToken)
+	assert.NilError(t, err)
+	_, err = authService.GetUserFromToken(first.Token)
+	assert.Error(t, err, "Token revoked")
+	_, err = authService.Refresh(first.RefreshToken)
+	assert.Error(t, err, "Invalid refresh token")
+
+	user, err := authService.GetUserFromToken(second.Token)
+	assert.NilError(t, err)
+
+	// テストケース2: 全端末からログアウトすると発行済みのトークンがすべて使えなくなる
+	err = authService.LogoutAll(user.ID)
+	assert.NilError(t, err)
+	_, err = authService.GetUserFromToken(second.Token)
+	assert.Error(t, err, "Token revoked")
+	_, err = authService.Refresh(second.RefreshToken)
+	assert.Error(t, err, "Invalid refresh token")
+
+	// テストケース3: その後にログインしたトークンは使える
+	third, err := authService.Login("test@example.com", "password123")
+	assert.NilError(t, err)
+	_, err = authService.GetUserFromToken(third.Token)
+	assert.NilError(t, err)
+}
+
+func TestVerifyEmail(t *testing.T) {
+	authService, db := setupAuthServiceTest()
+	defer db.Exec("DELETE FROM users")
+	defer db.Exec("DELETE FROM user_tokens")
+	defer db.Exec("DELETE FROM outbox_mails")
+	db.Exec("DELETE FROM outbox_mails")
+
+	// 送信済みの確認メールからトークンを取り出す
+	lastToken := func() string {
+		var outboxMail models.OutboxMail
+		assert.NilError(t, db.Last(&outboxMail).Error)
+		assert.Equal(t, "test@example.com", outboxMail.To)
+		_, rest, found := strings.Cut(outboxMail.Body, "token=")
+		assert.Assert(t, found)
+		token, _, _ := strings.Cut(rest, "\n")
+		return token
+	}
+
+	// テストケース1: 登録直後は未確認で、確認メールが送られる
+	err := authService.Signup("test@example.com", "password123")
+	assert.NilError(t, err)
+	var user models.User
+	assert.NilError(t, db.First(&user, "email = ?", "test@example.com").Error)
+	assert.Assert(t, user.EmailVerifiedAt == nil)
+	token := lastToken()
+
+	// テストケース2: 直後には送り直さず、しばらく経ってから送り直すと新しいトークンが届き、どちらのトークンでも確認できる
+	err = authService.ResendVerification("test@example.com")
+	assert.NilError(t, err)
+	assert.Equal(t, token, lastToken())
+
+	db.Model(&models.UserToken{}).Where("user_id = ?", user.ID).Update("created_at", time.Now().Add(-userTokenCooldown))
+	err = authService.ResendVerification("test@example.com")
+	assert.NilError(t, err)
+	assert.Assert(t, lastToken() != token)
+
+	err = authService.VerifyEmail(token)
+	assert.NilError(t, err)
+	assert.NilError(t, db.First(&user, user.ID).Error)
+	assert.Assert(t, user.EmailVerifiedAt != nil)
+
+	// テストケース3: 使用済みのトークンや不正なトークンは使えない
+	err = authService.VerifyEmail(token)
+	assert.Error(t, err, "Invalid token")
+	err = authService.VerifyEmail("invalid")
+	assert.Error(t, err, "Invalid token")
+
+	// テストケース4: 確認済みのユーザーや存在しないユーザーには送らない
+	var count int64
+	db.Model(&models.OutboxMail{}).Count(&count)
+	assert.NilError(t, authService.ResendVerification("test@example.com"))
+	assert.NilError(t, authService.ResendVerification("unknown@example.com"))
+	var after int64
+	db.Model(&models.OutboxMail{}).Count(&after)
+	assert.Equal(t, count, after)
+}
+
+func TestPasswordReset(t *testing.T) {
+	authService, db := setupAuthServiceTest()
+	defer db.Exec("DELETE FROM users")
+	defer db.Exec("DELETE FROM refresh_tokens")
+	defer db.Exec("DELETE FROM user_tokens")
+	defer db.Exec("DELETE FROM outbox_mails")
+
+	err := authService.Signup("test@example.com", "password123")
+	assert.NilError(t, err)
+	tokens, err := authService.Login("test@example.com", "password123")
+	assert.NilError(t, err)
+
+	// テストケース1: 未登録のメールアドレスには送らない
+	db.Exec("DELETE FROM outbox_mails")
+	err = authService.ForgotPassword("unknown@example.com")
+	assert.NilError(t, err)
+	var count int64
+	db.Model(&models.OutboxMail{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	// テストケース2: 直後には送り直さず、しばらく経ってから送り直すと新しいトークンが届く
+	err = authService.ForgotPassword("test@example.com")
+	assert.NilError(t, err)
+	err = authService.ForgotPassword("test@example.com")
+	assert.NilError(t, err)
+	db.Model(&models.OutboxMail{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	db.Model(&models.UserToken{}).Where("purpose = ?", models.UserTokenPurposePasswordReset).Update("created_at", time.Now().Add(-userTokenCooldown))
+	err = authService.ForgotPassword("test@example.com")
+	assert.NilError(t, err)
+	var outboxMails []models.OutboxMail
+	db.Order("id").Find(&outboxMails)
+	assert.Equal(t, 2, len(outboxMails))
+
+	// テストケース3: 再設定するとパスワードが変わり、発行済みのトークンが失効する
+	resetTokens := []string{}
+	for _, outboxMail := range outboxMails {
+		resetTokens = append(resetTokens, strings.Split(outboxMail.Body, "\n")[1])
+	}
+
+	err = authService.ResetPassword(resetTokens[1], "newpassword123")
+	assert.NilError(t, err)
+	_, err = authService.GetUserFromToken(tokens.Token)
+	assert.Error(t, err, "Token revoked")
+	_, err = authService.Refresh(tokens.RefreshToken)
+	assert.Error(t, err, "Invalid refresh token")
+	_, err = authService.Login("test@example.com", "password123")
+	assert.Assert(t, err != nil)
+	tokens, err = authService.Login("test@example.com", "newpassword123")
+	assert.NilError(t, err)
+
+	// テストケース4: 使用済みのトークンや、先に送った再設定用のトークンは使えない
+	err = authService.ResetPassword(resetTokens[1], "password123")
+	assert.Error(t, err, "Invalid token")
+	err = authService.ResetPassword(resetTokens[0], "password123")
+	assert.Error(t, err, "Invalid token")
+
+	// テストケース5: 現在のパスワードが違うと変更できない
+	user, err := authService.GetUserFromToken(tokens.Token)
+	assert.NilError(t, err)
+	assert.Assert(t, user.EmailVerifiedAt != nil)
+	err = authService.ChangePassword(user.ID, "password123", "changed123")
+	assert.Error(t, err, "Invalid password")
+
+	// テストケース6: 変更すると発行済みのトークンが失効する
+	err = authService.ChangePassword(user.ID, "newpassword123", "changed123")
+	assert.NilError(t, err)
+	_, err = authService.GetUserFromToken(tokens.Token)
+	assert.Error(t, err, "Token revoked")
+	_, err = authService.Login("test@example.com", "changed123")
+	assert.NilError(t, err)
+}
+
 func TestGetUserFromToken(t *testing.T) {
 	authService, db := setupAuthServiceTest()
 	defer db.Exec("DELETE FROM users")
@@ -106,7 +329,9 @@ func TestGetUserFromToken(t *testing.T) {
 	db.First(&user, "email = ?", "test@example.com")
 
 	// テスト用トークンを作成
-	token, err := CreateToken(user.ID, user.Email)
+	tokenKeys, err := SetupTokenKeys()
+	assert.NilError(t, err)
+	token, err := createToken(tokenKeys, user.ID, user.Email, user.TokenVersion)
 	assert.NilError(t, err)
 	assert.Assert(t, token != nil)
 
@@ -138,7 +363,9 @@ func TestCreateToken(t *testing.T) {
 	os.Setenv("SECRET_KEY", "test-secret-key")
 
 	// テストケース1: トークンの作成
-	token, err := CreateToken(1, "test@example.com")
+	tokenKeys, err := SetupTokenKeys()
+	assert.NilError(t, err)
+	token, err := createToken(tokenKeys, 1, "test@example.com", 2)
 	assert.NilError(t, err)
 	assert.Assert(t, token != nil)
 
@@ -153,6 +380,8 @@ func TestCreateToken(t *testing.T) {
 	if claims, ok := parsedToken.Claims.(jwt.MapClaims); ok {
 		assert.Equal(t, float64(1), claims["sub"])
 		assert.Equal(t, "test@example.com", claims["email"])
+		assert.Equal(t, float64(2), claims["ver"])
+		assert.Assert(t, claims["jti"] != "")
 		assert.Assert(t, claims["exp"] != nil)
 	} else {
 		t.Fail()
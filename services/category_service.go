@@ -0,0 +1,181 @@
+package services
+
+import (
+	"errors"
+	"gin-fleamarket/dto"
+	"gin-fleamarket/models"
+	"gin-fleamarket/repositories"
+)
+
+type ICategoryService interface {
+	FindTree() (*[]models.Category, error)
+	FindById(categoryId uint) (*models.Category, error)
+	Create(createCategoryInput dto.CreateCategoryInput) (*models.Category, error)
+	Update(categoryId uint, updateCategoryInput dto.UpdateCategoryInput) (*models.Category, error)
+	Delete(categoryId uint) error
+	IsLeaf(categoryId uint) (bool, error)
+	FindDescendantIds(categoryId uint) ([]uint, error)
+}
+
+type CategoryService struct {
+	repository     repositories.ICategoryRepository
+	itemRepository repositories.IItemRepository
+}
+
+func NewCategoryService(repository repositories.ICategoryRepository, itemRepository repositories.IItemRepository) ICategoryService {
+	return &CategoryService{repository: repository, itemRepository: itemRepository}
+}
+
+// FindTree はルートカテゴリのスライスを子カテゴリを入れ子にして返します
+func (s *CategoryService) FindTree() (*[]models.Category, error) {
+	categories, err := s.repository.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	children := childrenByParent(*categories)
+
+	var build func(parentId uint) []models.Category
+	build = func(parentId uint) []models.Category {
+		nodes := []models.Category{}
+		for _, child := range children[parentId] {
+			child.Children = build(child.ID)
+			nodes = append(nodes, child)
+		}
+		return nodes
+	}
+	tree := build(0)
+	return &tree, nil
+}
+
+func (s *CategoryService) FindById(categoryId uint) (*models.Category, error) {
+	category, err := s.repository.FindById(categoryId)
+	if err != nil {
+		return nil, err
+	}
+	categories, err := s.repository.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	category.Children = childrenByParent(*categories)[category.ID]
+	return category, nil
+}
+
+func (s *CategoryService) Create(createCategoryInput dto.CreateCategoryInput) (*models.Category, error) {
+	if createCategoryInput.ParentID != nil {
+		if err := s.checkParent(*createCategoryInput.ParentID); err != nil {
+			return nil, err
+		}
+	}
+	newCategory := models.Category{
+		Name:     createCategoryInput.Name,
+		ParentID: createCategoryInput.ParentID,
+	}
+	return s.repository.Create(newCategory)
+}
+
+func (s *CategoryService) Update(categoryId uint, updateCategoryInput dto.UpdateCategoryInput) (*models.Category, error) {
+	targetCategory, err := s.repository.FindById(categoryId)
+	if err != nil {
+		return nil, err
+	}
+
+	if updateCategoryInput.Name != nil {
+		targetCategory.Name = *updateCategoryInput.Name
+	}
+	if updateCategoryInput.ParentID != nil {
+		if *updateCategoryInput.ParentID == 0 {
+			targetCategory.ParentID = nil
+		} else {
+			// 自分自身や子孫の下には移動できない
+			descendantIds, err := s.FindDescendantIds(categoryId)
+			if err != nil {
+				return nil, err
+			}
+			for _, id := range descendantIds {
+				if id == *updateCategoryInput.ParentID {
+					return nil, errors.New("Invalid parent category")
+				}
+			}
+			if err := s.checkParent(*updateCategoryInput.ParentID); err != nil {
+				return nil, err
+			}
+			targetCategory.ParentID = updateCategoryInput.ParentID
+		}
+	}
+	return s.repository.Update(*targetCategory)
+}
+
+func (s *CategoryService) Delete(categoryId uint) error {
+	if _, err := s.repository.FindById(categoryId); err != nil {
+		return err
+	}
+	isLeaf, err := s.IsLeaf(categoryId)
+	if err != nil {
+		return err
+	}
+	itemCount, err := s.itemRepository.CountByCategory(categoryId)
+	if err != nil {
+		return err
+	}
+	if !isLeaf || itemCount > 0 {
+		return errors.New("Category in use")
+	}
+	return s.repository.Delete(categoryId)
+}
+
+func (s *CategoryService) IsLeaf(categoryId uint) (bool, error) {
+	categories, err := s.repository.FindAll()
+	if err != nil {
+		return false, err
+	}
+	return len(childrenByParent(*categories)[categoryId]) == 0, nil
+}
+
+// FindDescendantIds は指定したカテゴリ自身とその子孫のIDを返します
+func (s *CategoryService) FindDescendantIds(categoryId uint) ([]uint, error) {
+	categories, err := s.repository.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	children := childrenByParent(*categories)
+
+	ids := []uint{categoryId}
+	for i := 0; i < len(ids); i++ {
+		for _, child := range children[ids[i]] {
+			ids = append(ids, child.ID)
+		}
+	}
+	return ids, nil
+}
+
+// checkParent は親カテゴリが存在し、アイテムが紐付いていないことを確認します
+// アイテムを持つ末端カテゴリに子を作ると、アイテムが末端以外に属してしまうためです
+func (s *CategoryService) checkParent(parentId uint) error {
+	if _, err := s.repository.FindById(parentId); err != nil {
+		if err.Error() == "Category not found" {
+			return errors.New("Invalid parent category")
+		}
+		return err
+	}
+	itemCount, err := s.itemRepository.CountByCategory(parentId)
+	if err != nil {
+		return err
+	}
+	if itemCount > 0 {
+		return errors.New("Category in use")
+	}
+	return nil
+}
+
+// childrenByParent は親IDごとに子カテゴリをまとめます。ルートカテゴリのキーは0です
+func childrenByParent(categories []models.Category) map[uint][]models.Category {
+	children := map[uint][]models.Category{}
+	for _, category := range categories {
+		var parentId uint
+		if category.ParentID != nil {
+			parentId = *category.ParentID
+		}
+		children[parentId] = append(children[parentId], category)
+	}
+	return children
+}
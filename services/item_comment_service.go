@@ -0,0 +1,104 @@
+package services
+
+import (
+	"errors"
+	"gin-fleamarket/dto"
+	"gin-fleamarket/models"
+	"gin-fleamarket/repositories"
+	"slices"
+)
+
+type IItemCommentService interface {
+	FindByItem(itemId uint, query dto.PageQuery) (*dto.ItemCommentPage, error)
+	Create(itemId uint, userId uint, input dto.CreateItemCommentInput) (*models.ItemComment, error)
+	Delete(itemId uint, commentId uint, user models.User) error
+}
+
+// ItemCommentService は出品への質問と出品者の回答を扱います
+// 購入済みのアイテムにはコメントできなくなります。質問が投稿されると出品者にお知らせします
+type ItemCommentService struct {
+	repository             repositories.IItemCommentRepository
+	itemRepository         repositories.IItemRepository
+	notificationRepository repositories.INotificationRepository
+}
+
+func NewItemCommentService(repository repositories.IItemCommentRepository, itemRepository repositories.IItemRepository, notificationRepository repositories.INotificationRepository) IItemCommentService {
+	return &ItemCommentService{repository: repository, itemRepository: itemRepository, notificationRepository: notificationRepository}
+}
+
+const defaultCommentPageLimit = 20
+
+func (s *ItemCommentService) FindByItem(itemId uint, query dto.PageQuery) (*dto.ItemCommentPage, error) {
+	if _, err := s.itemRepository.FindVisibleById(itemId, publicItemStatuses); err != nil {
+		return nil, err
+	}
+	limit := query.Limit
+	if limit == 0 {
+		limit = defaultCommentPageLimit
+	}
+	comments, total, err := s.repository.FindByItem(itemId, limit, query.Offset)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.ItemCommentPage{Comments: *comments, Total: total}, nil
+}
+
+func (s *ItemCommentService) Create(itemId uint, userId uint, input dto.CreateItemCommentInput) (*models.ItemComment, error) {
+	item, err := s.itemRepository.FindVisibleById(itemId, publicItemStatuses)
+	if err != nil {
+		return nil, err
+	}
+	if slices.Contains(soldOutStatuses, item.Status) {
+		return nil, errors.New("Comments locked")
+	}
+	if input.ParentID != nil {
+		if item.UserID != userId {
+			return nil, errors.New("Forbidden")
+		}
+		// 回答への回答はできない
+		parent, err := s.repository.FindById(itemId, *input.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		if parent.ParentID != nil {
+			return nil, errors.New("Comment not found")
+		}
+	}
+
+	comment, err := s.repository.Create(models.ItemComment{
+		ItemID:   itemId,
+		UserID:   userId,
+		ParentID: input.ParentID,
+		Body:     input.Body,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if comment.ParentID == nil && item.UserID != userId {
+		if _, err := s.notificationRepository.Create(models.Notification{
+			UserID:    item.UserID,
+			Type:      models.NotificationTypeItemQuestion,
+			ItemID:    &item.ID,
+			CommentID: &comment.ID,
+			Message:   "「" + item.Name + "」に質問が届きました",
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return comment, nil
+}
+
+// Delete はコメントを削除します。削除できるのは出品者と管理者のみです
+func (s *ItemCommentService) Delete(itemId uint, commentId uint, user models.User) error {
+	item, err := s.itemRepository.FindVisibleById(itemId, publicItemStatuses)
+	if err != nil {
+		return err
+	}
+	if item.UserID != user.ID && !user.IsAdmin {
+		return errors.New("Forbidden")
+	}
+	if _, err := s.repository.FindById(itemId, commentId); err != nil {
+		return err
+	}
+	return s.repository.Delete(commentId)
+}
@@ -0,0 +1,240 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gin-fleamarket/infra"
+	"gin-fleamarket/models"
+	"gin-fleamarket/repositories"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"slices"
+)
+
+const (
+	// MaxImagesPerItem はアイテムに登録できる画像の枚数です。1回のアップロードで受け付ける枚数の上限にもなります
+	MaxImagesPerItem = 10
+	// maxImageDimension は受け付ける画像の幅と高さの上限です。デコード後のメモリ使用量を抑えるため、デコード前に確認します
+	maxImageDimension = 8000
+	thumbnailSize     = 300
+)
+
+type IItemImageService interface {
+	Upload(itemId uint, userId uint, contents [][]byte) (*[]models.ItemImage, error)
+	Reorder(itemId uint, userId uint, imageIds []uint) (*[]models.ItemImage, error)
+	Delete(itemId uint, userId uint, imageId uint) error
+}
+
+type ItemImageService struct {
+	repository     repositories.IItemImageRepository
+	itemRepository repositories.IItemRepository
+	storage        infra.IStorage
+}
+
+func NewItemImageService(repository repositories.IItemImageRepository, itemRepository repositories.IItemRepository, storage infra.IStorage) IItemImageService {
+	return &ItemImageService{repository: repository, itemRepository: itemRepository, storage: storage}
+}
+
+type decodedImage struct {
+	content []byte
+	image   image.Image
+	format  string
+}
+
+func (s *ItemImageService) Upload(itemId uint, userId uint, contents [][]byte) (*[]models.ItemImage, error) {
+	if _, err := s.itemRepository.FindById(itemId, userId); err != nil {
+		return nil, err
+	}
+	// デコードする前に枚数を確認する。同時にアップロードされた場合に備えて、登録するときにもう一度確認する
+	images, err := s.repository.FindByItem(itemId)
+	if err != nil {
+		return nil, err
+	}
+	if len(*images)+len(contents) > MaxImagesPerItem {
+		return nil, errors.New("Too many images")
+	}
+
+	// 1枚でも不正な画像があれば何も保存しない。まずデコードせずに形式と大きさを確認する
+	for _, content := range contents {
+		config, _, err := image.DecodeConfig(bytes.NewReader(content))
+		if err != nil {
+			return nil, errors.New("Unsupported image format")
+		}
+		if config.Width > maxImageDimension || config.Height > maxImageDimension {
+			return nil, errors.New("Image too large")
+		}
+	}
+
+	// デコードした画像を同時に1枚しか持たないように、1枚ずつデコードしてストレージに保存する
+	stored := make([]models.ItemImage, 0, len(contents))
+	for _, content := range contents {
+		img, format, err := image.Decode(bytes.NewReader(content))
+		if err != nil {
+			s.discard(stored)
+			return nil, errors.New("Unsupported image format")
+		}
+		newImage, err := s.store(itemId, decodedImage{content: content, image: img, format: format})
+		if err != nil {
+			s.discard(stored)
+			return nil, err
+		}
+		stored = append(stored, *newImage)
+	}
+
+	// 同時にアップロードされても枚数の上限を超えたり表示順が重複したりしないように、アイテムをロックしてから登録する
+	var created *[]models.ItemImage
+	err = s.repository.Transaction(func(tx repositories.IItemImageRepository) error {
+		if _, err := tx.LockItem(itemId); err != nil {
+			return err
+		}
+		images, err := tx.FindByItem(itemId)
+		if err != nil {
+			return err
+		}
+		if len(*images)+len(stored) > MaxImagesPerItem {
+			return errors.New("Too many images")
+		}
+		position := 0
+		if len(*images) > 0 {
+			position = (*images)[len(*images)-1].Position + 1
+		}
+		for _, newImage := range stored {
+			newImage.Position = position
+			createdImage, err := tx.Create(newImage)
+			if err != nil {
+				return err
+			}
+			*images = append(*images, *createdImage)
+			position++
+		}
+		created = images
+		return nil
+	})
+	if err != nil {
+		s.discard(stored)
+		return nil, err
+	}
+	return created, nil
+}
+
+// discard は登録できなかった画像のファイルをストレージから削除します
+func (s *ItemImageService) discard(images []models.ItemImage) {
+	for _, image := range images {
+		s.storage.Delete(image.Key)
+		s.storage.Delete(image.ThumbnailKey)
+	}
+}
+
+// store は画像とサムネイルをストレージに保存し、登録前の画像を返します
+func (s *ItemImageService) store(itemId uint, d decodedImage) (*models.ItemImage, error) {
+	name, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("items/%d/%s.%s", itemId, name, d.format)
+	thumbnailKey := fmt.Sprintf("items/%d/%s_thumb.jpg", itemId, name)
+
+	thumbnail, err := encodeThumbnail(d.image)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.storage.Save(key, bytes.NewReader(d.content)); err != nil {
+		return nil, err
+	}
+	if err := s.storage.Save(thumbnailKey, bytes.NewReader(thumbnail)); err != nil {
+		s.storage.Delete(key)
+		return nil, err
+	}
+
+	bounds := d.image.Bounds()
+	return &models.ItemImage{
+		ItemID:       itemId,
+		Key:          key,
+		ThumbnailKey: thumbnailKey,
+		URL:          s.storage.URL(key),
+		ThumbnailURL: s.storage.URL(thumbnailKey),
+		ContentType:  "image/" + d.format,
+		Width:        bounds.Dx(),
+		Height:       bounds.Dy(),
+	}, nil
+}
+
+// Reorder は imageIds の順に画像の表示順を並べ替えます。アイテムの全画像を指定する必要があります
+func (s *ItemImageService) Reorder(itemId uint, userId uint, imageIds []uint) (*[]models.ItemImage, error) {
+	if _, err := s.itemRepository.FindById(itemId, userId); err != nil {
+		return nil, err
+	}
+	images, err := s.repository.FindByItem(itemId)
+	if err != nil {
+		return nil, err
+	}
+	if len(imageIds) != len(*images) {
+		return nil, errors.New("Invalid image order")
+	}
+
+	reordered := make([]models.ItemImage, 0, len(imageIds))
+	for position, imageId := range imageIds {
+		index := slices.IndexFunc(*images, func(image models.ItemImage) bool { return image.ID == imageId })
+		if index < 0 || slices.Index(imageIds, imageId) != position {
+			return nil, errors.New("Invalid image order")
+		}
+		image := (*images)[index]
+		image.Position = position
+		reordered = append(reordered, image)
+	}
+	if err := s.repository.UpdatePositions(reordered); err != nil {
+		return nil, err
+	}
+	return &reordered, nil
+}
+
+func (s *ItemImageService) Delete(itemId uint, userId uint, imageId uint) error {
+	if _, err := s.itemRepository.FindById(itemId, userId); err != nil {
+		return err
+	}
+	images, err := s.repository.FindByItem(itemId)
+	if err != nil {
+		return err
+	}
+	index := slices.IndexFunc(*images, func(image models.ItemImage) bool { return image.ID == imageId })
+	if index < 0 {
+		return errors.New("Image not found")
+	}
+
+	target := (*images)[index]
+	if err := s.repository.Delete(target.ID); err != nil {
+		return err
+	}
+	if err := s.storage.Delete(target.Key); err != nil {
+		return err
+	}
+	return s.storage.Delete(target.ThumbnailKey)
+}
+
+// encodeThumbnail は縮小した画像を白背景に合成してJPEGにエンコードします
+func encodeThumbnail(src image.Image) ([]byte, error) {
+	resized := resizeToFit(src, thumbnailSize)
+	canvas := image.NewRGBA(resized.Bounds())
+	draw.Draw(canvas, canvas.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(canvas, canvas.Bounds(), resized, resized.Bounds().Min, draw.Over)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, canvas, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
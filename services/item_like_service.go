@@ -0,0 +1,91 @@
+package services
+
+import (
+	"gin-fleamarket/dto"
+	"gin-fleamarket/models"
+	"gin-fleamarket/repositories"
+	"slices"
+
+	"gorm.io/gorm"
+)
+
+type IItemLikeService interface {
+	Like(itemId uint, userId uint) (*dto.LikeOutput, error)
+	Unlike(itemId uint, userId uint) (*dto.LikeOutput, error)
+	FindMine(userId uint, query dto.PageQuery) (*dto.LikedItemPage, error)
+}
+
+type ItemLikeService struct {
+	repository     repositories.IItemLikeRepository
+	itemRepository repositories.IItemRepository
+}
+
+func NewItemLikeService(repository repositories.IItemLikeRepository, itemRepository repositories.IItemRepository) IItemLikeService {
+	return &ItemLikeService{repository: repository, itemRepository: itemRepository}
+}
+
+const defaultLikePageLimit = 20
+
+// Like は公開中のアイテムにいいねします。既にいいねしている場合も成功として扱います
+func (s *ItemLikeService) Like(itemId uint, userId uint) (*dto.LikeOutput, error) {
+	if _, err := s.itemRepository.FindVisibleById(itemId, publicItemStatuses); err != nil {
+		return nil, err
+	}
+	likeCount, err := s.repository.Like(userId, itemId)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.LikeOutput{ItemID: itemId, Liked: true, LikeCount: likeCount}, nil
+}
+
+// Unlike はいいねを取り消します。削除されたアイテムのいいねも取り消せます
+func (s *ItemLikeService) Unlike(itemId uint, userId uint) (*dto.LikeOutput, error) {
+	likeCount, err := s.repository.Unlike(userId, itemId)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.LikeOutput{ItemID: itemId, Liked: false, LikeCount: likeCount}, nil
+}
+
+// FindMine はいいねしたアイテムを、いいねした新しい順に返します
+// いいねした後に購入済みになったアイテムや削除されたアイテムも、それぞれのフラグを立てて返します
+func (s *ItemLikeService) FindMine(userId uint, query dto.PageQuery) (*dto.LikedItemPage, error) {
+	limit := query.Limit
+	if limit == 0 {
+		limit = defaultLikePageLimit
+	}
+	likes, total, err := s.repository.FindByUser(userId, limit, query.Offset)
+	if err != nil {
+		return nil, err
+	}
+	itemIds := make([]uint, 0, len(*likes))
+	for _, like := range *likes {
+		itemIds = append(itemIds, like.ItemID)
+	}
+	items, err := s.repository.FindItems(itemIds)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := make([]dto.LikedItemOutput, 0, len(*likes))
+	for _, like := range *likes {
+		index := slices.IndexFunc(*items, func(item models.Item) bool { return item.ID == like.ItemID })
+		if index < 0 {
+			continue
+		}
+		item := (*items)[index]
+		output := dto.LikedItemOutput{
+			Item:    item,
+			LikedAt: like.CreatedAt,
+			SoldOut: slices.Contains(soldOutStatuses, item.Status),
+			Deleted: item.DeletedAt.Valid || !slices.Contains(publicItemStatuses, item.Status),
+		}
+		// 削除や非公開になったアイテムは中身を返さない
+		if output.Deleted {
+			output.Item = models.Item{Model: gorm.Model{ID: item.ID}}
+			output.SoldOut = false
+		}
+		outputs = append(outputs, output)
+	}
+	return &dto.LikedItemPage{Items: outputs, Total: total}, nil
+}
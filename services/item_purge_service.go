@@ -0,0 +1,74 @@
+package services
+
+import (
+	"gin-fleamarket/infra"
+	"gin-fleamarket/repositories"
+	"log"
+	"time"
+)
+
+type IItemPurgeService interface {
+	PurgeExpired(now time.Time) (int, error)
+	Run(interval time.Duration)
+}
+
+// ItemPurgeService はゴミ箱に入ってから保持期間を過ぎたアイテムを物理削除します
+type ItemPurgeService struct {
+	repository      repositories.IItemRepository
+	imageRepository repositories.IItemImageRepository
+	storage         infra.IStorage
+	retention       time.Duration
+}
+
+func NewItemPurgeService(repository repositories.IItemRepository, imageRepository repositories.IItemImageRepository, storage infra.IStorage, retention time.Duration) IItemPurgeService {
+	return &ItemPurgeService{
+		repository:      repository,
+		imageRepository: imageRepository,
+		storage:         storage,
+		retention:       retention,
+	}
+}
+
+func (s *ItemPurgeService) PurgeExpired(now time.Time) (int, error) {
+	items, err := s.repository.FindDeletedBefore(now.Add(-s.retention))
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, item := range *items {
+		images, err := s.imageRepository.FindByItem(item.ID)
+		if err != nil {
+			return purged, err
+		}
+		if err := s.repository.Purge(item.ID); err != nil {
+			return purged, err
+		}
+		for _, image := range *images {
+			if err := s.storage.Delete(image.Key); err != nil {
+				log.Printf("Failed to delete image %s: %v", image.Key, err)
+			}
+			if err := s.storage.Delete(image.ThumbnailKey); err != nil {
+				log.Printf("Failed to delete image %s: %v", image.ThumbnailKey, err)
+			}
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// Run は interval ごとに PurgeExpired を実行し続けます。goroutine で呼び出してください
+func (s *ItemPurgeService) Run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		purged, err := s.PurgeExpired(now)
+		if err != nil {
+			log.Printf("Failed to purge deleted items: %v", err)
+			continue
+		}
+		if purged > 0 {
+			log.Printf("Purged %d deleted items", purged)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"bytes"
+	"gin-fleamarket/infra"
+	"gin-fleamarket/models"
+	"gin-fleamarket/repositories"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gorm.io/gorm"
+	"gotest.tools/v3/assert"
+)
+
+func setupItemPurgeServiceTest(t *testing.T) (IItemPurgeService, *gorm.DB, infra.IStorage, string) {
+	// テスト環境の読み込み
+	if err := godotenv.Load("../.env.test"); err != nil {
+		os.Setenv("ENV", "test")
+	}
+
+	db := infra.SetupDB()
+	db.AutoMigrate(&models.Item{}, &models.ItemImage{}, &models.Auction{}, &models.Bid{}, &models.ItemComment{}, &models.ItemLike{}, &models.Offer{}, &models.Order{})
+
+	storageDir := t.TempDir()
+	storage := infra.NewLocalStorage(storageDir, "/uploads")
+	purgeService := NewItemPurgeService(
+		repositories.NewItemRepository(db),
+		repositories.NewItemImageRepository(db),
+		storage,
+		24*time.Hour,
+	)
+	return purgeService, db, storage, storageDir
+}
+
+func TestPurgeExpired(t *testing.T) {
+	purgeService, db, storage, storageDir := setupItemPurgeServiceTest(t)
+
+	// 削除済みのアイテムと画像を用意する
+	item := models.Item{Name: "削除済み", Price: 1000, Status: models.ItemStatusListed, UserID: 1}
+	db.Create(&item)
+	image := models.ItemImage{ItemID: item.ID, Key: "items/1/a.png", ThumbnailKey: "items/1/a_thumb.jpg", URL: "/uploads/items/1/a.png", ThumbnailURL: "/uploads/items/1/a_thumb.jpg", ContentType: "image/png"}
+	db.Create(&image)
+	assert.NilError(t, storage.Save(image.Key, bytes.NewReader([]byte("original"))))
+	assert.NilError(t, storage.Save(image.ThumbnailKey, bytes.NewReader([]byte("thumbnail"))))
+	db.Delete(&item)
+
+	// テストケース1: 保持期間内は削除されない
+	purged, err := purgeService.PurgeExpired(time.Now())
+	assert.NilError(t, err)
+	assert.Equal(t, 0, purged)
+
+	// テストケース2: 保持期間を過ぎると画像ごと物理削除される
+	purged, err = purgeService.PurgeExpired(time.Now().Add(25 * time.Hour))
+	assert.NilError(t, err)
+	assert.Equal(t, 1, purged)
+
+	var count int64
+	db.Unscoped().Model(&models.Item{}).Where("id = ?", item.ID).Count(&count)
+	assert.Equal(t, int64(0), count)
+	db.Unscoped().Model(&models.ItemImage{}).Where("item_id = ?", item.ID).Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	_, err = os.Stat(filepath.Join(storageDir, "items", "1", "a.png"))
+	assert.Assert(t, os.IsNotExist(err))
+
+	// テストケース3: 注文から参照されているアイテムは物理削除しない
+	orderedItem := models.Item{Name: "取引済み", Price: 1000, Status: models.ItemStatusCompleted, UserID: 1}
+	db.Create(&orderedItem)
+	db.Create(&models.Order{ItemID: orderedItem.ID, BuyerID: 2, SellerID: 1, Price: 1000, Status: models.OrderStatusCompleted})
+	db.Delete(&orderedItem)
+
+	purged, err = purgeService.PurgeExpired(time.Now().Add(25 * time.Hour))
+	assert.NilError(t, err)
+	assert.Equal(t, 0, purged)
+	db.Unscoped().Model(&models.Item{}).Where("id = ?", orderedItem.ID).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
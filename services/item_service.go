@@ -1,51 +1,321 @@
 package services
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"gin-fleamarket/dto"
 	"gin-fleamarket/models"
 	"gin-fleamarket/repositories"
+	"slices"
+	"time"
 )
 
 type IItemService interface {
-	FindAll() (*[]models.Item, error)
+	FindAll(query dto.FindItemsQuery) (*dto.ItemPage, error)
+	FindMine(userId uint, query dto.FindItemsQuery) (*dto.ItemPage, error)
+	CountMineByStatus(userId uint) (map[string]int64, error)
 	FindById(itemId uint, userId uint) (*models.Item, error)
+	FindPublicById(itemId uint) (*dto.ItemDetailOutput, error)
+	Search(query dto.SearchItemsQuery) (*dto.ItemPage, error)
 	Create(createItemInput dto.CreateItemInput, userId uint) (*models.Item, error)
-	Update(itemId uint, userId uint, updateItemInput dto.UpdateItemInput) (*models.Item, error)
-	Delete(itemId uint, userId uint) error
+	Update(itemId uint, userId uint, version uint, updateItemInput dto.UpdateItemInput) (*models.Item, error)
+	Delete(itemId uint, userId uint, version uint) error
+	FindTrash(userId uint, query dto.PageQuery) (*dto.ItemPage, error)
+	Restore(itemId uint, userId uint) (*models.Item, error)
 }
 
 type ItemService struct {
-	repository repositories.IItemRepository
+	repository      repositories.IItemRepository
+	userRepository  repositories.IUserRepository
+	searchService   ISearchService
+	categoryService ICategoryService
 }
 
-func NewItemService(repository repositories.IItemRepository) IItemService {
-	return &ItemService{repository: repository}
+func NewItemService(repository repositories.IItemRepository, userRepository repositories.IUserRepository, searchService ISearchService, categoryService ICategoryService) IItemService {
+	return &ItemService{
+		repository:      repository,
+		userRepository:  userRepository,
+		searchService:   searchService,
+		categoryService: categoryService,
+	}
+}
+
+const defaultItemPageLimit = 20
+
+// FindAll は公開中のアイテムだけを返します
+func (s *ItemService) FindAll(query dto.FindItemsQuery) (*dto.ItemPage, error) {
+	statuses := resolveItemStatuses(query.Status, query.SoldOut)
+	if statuses == nil {
+		statuses = publicItemStatuses
+	} else {
+		statuses = slices.DeleteFunc(statuses, func(status string) bool {
+			return !slices.Contains(publicItemStatuses, status)
+		})
+	}
+	return s.findPage(query, query.UserID, statuses)
+}
+
+// FindMine はログインユーザーのアイテムを下書きなども含めて返します
+func (s *ItemService) FindMine(userId uint, query dto.FindItemsQuery) (*dto.ItemPage, error) {
+	return s.findPage(query, &userId, resolveItemStatuses(query.Status, query.SoldOut))
+}
+
+func (s *ItemService) CountMineByStatus(userId uint) (map[string]int64, error) {
+	counts, err := s.repository.CountByStatus(userId)
+	if err != nil {
+		return nil, err
+	}
+	for status := range itemStatusTransitions {
+		if _, ok := counts[status]; !ok {
+			counts[status] = 0
+		}
+	}
+	return counts, nil
+}
+
+func (s *ItemService) findPage(query dto.FindItemsQuery, userId *uint, statuses []string) (*dto.ItemPage, error) {
+	filter := repositories.ItemFilter{
+		MinPrice: query.MinPrice,
+		MaxPrice: query.MaxPrice,
+		Statuses: statuses,
+		UserID:   userId,
+		Sort:     query.Sort,
+		Limit:    query.Limit,
+		Offset:   query.Offset,
+	}
+	if filter.Statuses != nil && len(filter.Statuses) == 0 {
+		return &dto.ItemPage{Items: []models.Item{}, Total: 0}, nil
+	}
+	if filter.Sort == "" {
+		filter.Sort = repositories.ItemSortNewest
+	}
+	if filter.Limit == 0 {
+		filter.Limit = defaultItemPageLimit
+	}
+	if query.Category != nil {
+		categoryIds, err := s.categoryService.FindDescendantIds(*query.Category)
+		if err != nil {
+			return nil, err
+		}
+		filter.CategoryIDs = categoryIds
+	}
+	if query.Cursor != "" {
+		cursor, err := decodeItemCursor(query.Cursor)
+		if err != nil || cursor.Sort != filter.Sort {
+			return nil, errors.New("Invalid cursor")
+		}
+		filter.Cursor = cursor
+	}
+
+	page, err := s.repository.FindAll(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &dto.ItemPage{Items: page.Items, Total: page.Total}
+	if page.NextCursor != nil {
+		nextCursor, err := encodeItemCursor(page.NextCursor)
+		if err != nil {
+			return nil, err
+		}
+		result.NextCursor = &nextCursor
+	}
+	return result, nil
+}
+
+func (s *ItemService) Search(query dto.SearchItemsQuery) (*dto.ItemPage, error) {
+	limit := query.Limit
+	if limit == 0 {
+		limit = defaultItemPageLimit
+	}
+	itemIds, total, err := s.searchService.Search(query.Q, limit, query.Offset)
+	if err != nil {
+		return nil, err
+	}
+	if len(itemIds) == 0 {
+		return &dto.ItemPage{Items: []models.Item{}, Total: total}, nil
+	}
+
+	found, err := s.repository.FindVisibleByIds(itemIds, publicItemStatuses)
+	if err != nil {
+		return nil, err
+	}
+	// 関連度順を保つために検索結果の順序で並べ直す
+	byId := make(map[uint]models.Item, len(*found))
+	for _, item := range *found {
+		byId[item.ID] = item
+	}
+	items := make([]models.Item, 0, len(itemIds))
+	for _, itemId := range itemIds {
+		if item, ok := byId[itemId]; ok {
+			items = append(items, item)
+		}
+	}
+	return &dto.ItemPage{Items: items, Total: total}, nil
+}
+
+func (s *ItemService) FindTrash(userId uint, query dto.PageQuery) (*dto.ItemPage, error) {
+	limit := query.Limit
+	if limit == 0 {
+		limit = defaultItemPageLimit
+	}
+	items, total, err := s.repository.FindTrash(userId, limit, query.Offset)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.ItemPage{Items: *items, Total: total}, nil
+}
+
+func (s *ItemService) Restore(itemId uint, userId uint) (*models.Item, error) {
+	restoredItem, err := s.repository.Restore(itemId, userId)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.syncSearchIndex(*restoredItem); err != nil {
+		return nil, err
+	}
+	return restoredItem, nil
+}
+
+// syncSearchIndex は公開中のアイテムだけが検索にヒットするようにインデックスを更新します
+func (s *ItemService) syncSearchIndex(item models.Item) error {
+	if slices.Contains(publicItemStatuses, item.Status) {
+		return s.searchService.IndexItem(item)
+	}
+	return s.searchService.RemoveItem(item.ID)
+}
+
+// checkCategory はアイテムに設定するカテゴリが存在する末端カテゴリであることを確認します
+func (s *ItemService) checkCategory(categoryId uint) error {
+	if _, err := s.categoryService.FindById(categoryId); err != nil {
+		if err.Error() == "Category not found" {
+			return errors.New("Invalid category")
+		}
+		return err
+	}
+	isLeaf, err := s.categoryService.IsLeaf(categoryId)
+	if err != nil {
+		return err
+	}
+	if !isLeaf {
+		return errors.New("Invalid category")
+	}
+	return nil
+}
+
+func encodeItemCursor(cursor *repositories.ItemCursor) (string, error) {
+	b, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
 }
 
-func (s *ItemService) FindAll() (*[]models.Item, error) {
-	return s.repository.FindAll()
+func decodeItemCursor(encoded string) (*repositories.ItemCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	var cursor repositories.ItemCursor
+	if err := json.Unmarshal(b, &cursor); err != nil {
+		return nil, err
+	}
+	return &cursor, nil
 }
 
 func (s *ItemService) FindById(itemId uint, userId uint) (*models.Item, error) {
 	return s.repository.FindById(itemId, userId)
 }
 
+// FindPublicById は出品者に関係なく公開中のアイテムを出品者の情報と一緒に返します
+func (s *ItemService) FindPublicById(itemId uint) (*dto.ItemDetailOutput, error) {
+	item, err := s.repository.FindVisibleById(itemId, publicItemStatuses)
+	if err != nil {
+		return nil, err
+	}
+	seller, err := s.findSellerSummary(item.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.ItemDetailOutput{Item: *item, Seller: *seller}, nil
+}
+
+func (s *ItemService) findSellerSummary(userId uint) (*dto.SellerSummary, error) {
+	seller, err := s.userRepository.FindById(userId)
+	if err != nil {
+		return nil, err
+	}
+	listings, err := s.repository.FindAll(repositories.ItemFilter{
+		UserID:   &userId,
+		Statuses: []string{models.ItemStatusListed},
+		Limit:    1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &dto.SellerSummary{
+		ID:           seller.ID,
+		MemberSince:  seller.CreatedAt,
+		ListingCount: listings.Total,
+		Reputation:   reputationOf(*seller),
+	}, nil
+}
+
 func (s *ItemService) Create(createItemInput dto.CreateItemInput, userId uint) (*models.Item, error) {
+	if createItemInput.CategoryID != nil {
+		if err := s.checkCategory(*createItemInput.CategoryID); err != nil {
+			return nil, err
+		}
+	}
+	status := createItemInput.Status
+	if status == "" {
+		status = models.ItemStatusListed
+	}
+	listingType := createItemInput.ListingType
+	if listingType == "" {
+		listingType = models.ListingTypeFixed
+	}
 	newItem := models.Item{
 		Name:        createItemInput.Name,
 		Price:       createItemInput.Price,
 		Description: createItemInput.Description,
-		SoldOut:     false,
+		Status:      status,
 		UserID:      userId,
+		CategoryID:  createItemInput.CategoryID,
+		ListingType: listingType,
+	}
+	if listingType == models.ListingTypeAuction {
+		auction, err := newAuction(createItemInput, status)
+		if err != nil {
+			return nil, err
+		}
+		newItem.Auction = auction
 	}
-	return s.repository.Create(newItem)
+	createdItem, err := s.repository.Create(newItem)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.syncSearchIndex(*createdItem); err != nil {
+		return nil, err
+	}
+	return createdItem, nil
 }
 
-func (s *ItemService) Update(itemId uint, userId uint, updateItemInput dto.UpdateItemInput) (*models.Item, error) {
+// version には If-Match で指定されたバージョンを渡します。0の場合はバージョンを確認しません
+func (s *ItemService) Update(itemId uint, userId uint, version uint, updateItemInput dto.UpdateItemInput) (*models.Item, error) {
 	targetItem, err := s.FindById(itemId, userId)
 	if err != nil {
 		return nil, err
 	}
+	if version != 0 && targetItem.Version != version {
+		return nil, errors.New("Version conflict")
+	}
+
+	// オークションの価格と状態は入札と終了処理でのみ変わる
+	if targetItem.ListingType == models.ListingTypeAuction && (updateItemInput.Price != nil || updateItemInput.Status != nil) {
+		return nil, errors.New("Cannot change auction listing")
+	}
 
 	if updateItemInput.Name != nil {
 		targetItem.Name = *updateItemInput.Name
@@ -56,12 +326,81 @@ func (s *ItemService) Update(itemId uint, userId uint, updateItemInput dto.Updat
 	if updateItemInput.Description != nil {
 		targetItem.Description = *updateItemInput.Description
 	}
-	if updateItemInput.SoldOut != nil {
-		targetItem.SoldOut = *updateItemInput.SoldOut
+	if updateItemInput.Status != nil && *updateItemInput.Status != targetItem.Status {
+		if !canTransitionItemStatus(targetItem.Status, *updateItemInput.Status) {
+			return nil, errors.New("Invalid status transition")
+		}
+		if err := s.checkNotInTrade(*targetItem); err != nil {
+			return nil, err
+		}
+		targetItem.Status = *updateItemInput.Status
+	}
+	if updateItemInput.CategoryID != nil {
+		if err := s.checkCategory(*updateItemInput.CategoryID); err != nil {
+			return nil, err
+		}
+		targetItem.CategoryID = updateItemInput.CategoryID
+	}
+	updatedItem, err := s.repository.Update(*targetItem)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.syncSearchIndex(*updatedItem); err != nil {
+		return nil, err
+	}
+	return updatedItem, nil
+}
+
+// version には If-Match で指定されたバージョンを渡します。0の場合はバージョンを確認しません
+func (s *ItemService) Delete(itemId uint, userId uint, version uint) error {
+	targetItem, err := s.FindById(itemId, userId)
+	if err != nil {
+		return err
+	}
+	if version == 0 {
+		version = targetItem.Version
 	}
-	return s.repository.Update(*targetItem)
+	// 入札のあるオークションは取り下げられない
+	if auction := targetItem.Auction; auction != nil && auction.Status == models.AuctionStatusOpen && auction.BidCount > 0 {
+		return errors.New("Auction in progress")
+	}
+	if err := s.checkNotInTrade(*targetItem); err != nil {
+		return err
+	}
+	if err := s.repository.Delete(itemId, userId, version); err != nil {
+		return err
+	}
+	return s.searchService.RemoveItem(itemId)
 }
 
-func (s *ItemService) Delete(itemId uint, userId uint) error {
-	return s.repository.Delete(itemId, userId)
+// checkNotInTrade は取引中のアイテムに対して "Item in trade" を返します
+func (s *ItemService) checkNotInTrade(item models.Item) error {
+	if slices.Contains(tradingItemStatuses, item.Status) {
+		return errors.New("Item in trade")
+	}
+	inTrade, err := s.repository.HasActiveTrade(item.ID)
+	if err != nil {
+		return err
+	}
+	if inTrade {
+		return errors.New("Item in trade")
+	}
+	return nil
+}
+
+// newAuction は出品と同時に開始するオークションを作ります。下書きのままオークションを出品することはできません
+func newAuction(createItemInput dto.CreateItemInput, status string) (*models.Auction, error) {
+	if status != models.ItemStatusListed || createItemInput.Auction == nil {
+		return nil, errors.New("Invalid auction")
+	}
+	duration := time.Until(createItemInput.Auction.EndsAt)
+	if duration < minAuctionDuration || duration > maxAuctionDuration {
+		return nil, errors.New("Invalid auction end time")
+	}
+	return &models.Auction{
+		StartPrice:   createItemInput.Price,
+		ReservePrice: createItemInput.Auction.ReservePrice,
+		EndsAt:       createItemInput.Auction.EndsAt,
+		Status:       models.AuctionStatusOpen,
+	}, nil
 }
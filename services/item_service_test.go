@@ -0,0 +1,130 @@
+package services
+
+import (
+	"gin-fleamarket/dto"
+	"gin-fleamarket/models"
+	"gin-fleamarket/repositories"
+	"testing"
+
+	"gorm.io/gorm"
+	"gotest.tools/v3/assert"
+)
+
+func setupItemServiceTest() IItemService {
+	items := []models.Item{
+		{Model: gorm.Model{ID: 1}, Name: "商品1", Price: 1000, Description: "説明1", Status: models.ItemStatusListed, UserID: 1},
+		{Model: gorm.Model{ID: 2}, Name: "商品2", Price: 2000, Description: "説明2", Status: models.ItemStatusSold, UserID: 1},
+		{Model: gorm.Model{ID: 3}, Name: "商品3", Price: 3000, Description: "説明3", Status: models.ItemStatusListed, UserID: 2},
+		{Model: gorm.Model{ID: 4}, Name: "商品4", Price: 2000, Description: "説明4", Status: models.ItemStatusListed, UserID: 2},
+		{Model: gorm.Model{ID: 5}, Name: "商品5", Price: 4000, Description: "説明5", Status: models.ItemStatusDraft, UserID: 2},
+	}
+	itemRepository := repositories.NewItemMemoryRepository(items)
+	searchService := NewSearchService(repositories.NewSearchMemoryRepository(itemRepository))
+	categoryService := NewCategoryService(repositories.NewCategoryMemoryRepository([]models.Category{}), itemRepository)
+	userRepository := repositories.NewUserMemoryRepository([]models.User{{Model: gorm.Model{ID: 1}}, {Model: gorm.Model{ID: 2}}})
+	itemService := NewItemService(itemRepository, userRepository, searchService, categoryService)
+	// 非公開のアイテムがインデックスに残っている場合も含めて確認するため、すべてのアイテムを登録する
+	for _, item := range items {
+		searchService.IndexItem(item)
+	}
+	return itemService
+}
+
+func TestFindAllWithMemoryRepository(t *testing.T) {
+	itemService := setupItemServiceTest()
+
+	// テストケース1: デフォルトは新着順
+	page, err := itemService.FindAll(dto.FindItemsQuery{})
+	assert.NilError(t, err)
+	assert.Equal(t, int64(4), page.Total)
+	assert.Equal(t, uint(4), page.Items[0].ID)
+	assert.Assert(t, page.NextCursor == nil)
+
+	// テストケース2: 出品者と売り切れ状態で絞り込み
+	userId := uint(1)
+	soldOut := false
+	page, err = itemService.FindAll(dto.FindItemsQuery{UserID: &userId, SoldOut: &soldOut})
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(page.Items))
+	assert.Equal(t, uint(1), page.Items[0].ID)
+
+	// テストケース3: 価格の昇順でカーソルをたどる
+	var ids []uint
+	query := dto.FindItemsQuery{Sort: "price_asc", Limit: 1}
+	for {
+		page, err = itemService.FindAll(query)
+		assert.NilError(t, err)
+		for _, item := range page.Items {
+			ids = append(ids, item.ID)
+		}
+		if page.NextCursor == nil {
+			break
+		}
+		query.Cursor = *page.NextCursor
+	}
+	assert.DeepEqual(t, []uint{1, 2, 4, 3}, ids)
+
+	// テストケース4: オフセット指定
+	page, err = itemService.FindAll(dto.FindItemsQuery{Sort: "price_desc", Offset: 3})
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(page.Items))
+	assert.Equal(t, uint(1), page.Items[0].ID)
+}
+
+func TestSearchWithMemoryRepository(t *testing.T) {
+	itemService := setupItemServiceTest()
+
+	// テストケース1: 名前にも説明にも含まれるトークンで検索。下書きのアイテムは件数にも含まれない
+	page, err := itemService.Search(dto.SearchItemsQuery{Q: "商品"})
+	assert.NilError(t, err)
+	assert.Equal(t, int64(4), page.Total)
+	assert.Equal(t, 4, len(page.Items))
+
+	// テストケース2: 全角数字は半角に正規化される
+	page, err = itemService.Search(dto.SearchItemsQuery{Q: "説明３"})
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(page.Items))
+	assert.Equal(t, uint(3), page.Items[0].ID)
+
+	// テストケース3: 下書きのアイテムだけにマッチするクエリ
+	page, err = itemService.Search(dto.SearchItemsQuery{Q: "説明5"})
+	assert.NilError(t, err)
+	assert.Equal(t, int64(0), page.Total)
+	assert.Equal(t, 0, len(page.Items))
+
+	// テストケース4: 一致しないクエリ
+	page, err = itemService.Search(dto.SearchItemsQuery{Q: "腕時計"})
+	assert.NilError(t, err)
+	assert.Equal(t, 0, len(page.Items))
+}
+
+func TestTrashWithMemoryRepository(t *testing.T) {
+	itemService := setupItemServiceTest()
+
+	// テストケース1: 削除したアイテムはゴミ箱に入る
+	err := itemService.Delete(1, 1, 1)
+	assert.NilError(t, err)
+
+	page, err := itemService.FindTrash(1, dto.PageQuery{})
+	assert.NilError(t, err)
+	assert.Equal(t, int64(1), page.Total)
+	assert.Equal(t, uint(1), page.Items[0].ID)
+
+	_, err = itemService.FindById(1, 1)
+	assert.Error(t, err, "Item not found")
+
+	// テストケース2: 他人のアイテムは削除・復元できない
+	err = itemService.Delete(3, 1, 1)
+	assert.Error(t, err, "Item not found")
+	_, err = itemService.Restore(1, 2)
+	assert.Error(t, err, "Item not found")
+
+	// テストケース3: 復元すると元に戻る
+	restoredItem, err := itemService.Restore(1, 1)
+	assert.NilError(t, err)
+	assert.Equal(t, uint(1), restoredItem.ID)
+
+	page, err = itemService.FindTrash(1, dto.PageQuery{})
+	assert.NilError(t, err)
+	assert.Equal(t, int64(0), page.Total)
+}
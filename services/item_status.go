@@ -0,0 +1,75 @@
+package services
+
+import (
+	"gin-fleamarket/models"
+	"slices"
+)
+
+// itemStatusTransitions は出品ステータスごとに出品者が変更できる次のステータスを定義します
+// reserved, sold, shipped, completed への変更とそこからの変更は、注文・オファー・オークションの処理だけが行います
+var itemStatusTransitions = map[string][]string{
+	models.ItemStatusDraft:     {models.ItemStatusListed, models.ItemStatusCancelled},
+	models.ItemStatusListed:    {models.ItemStatusDraft, models.ItemStatusCancelled},
+	models.ItemStatusReserved:  {},
+	models.ItemStatusSold:      {},
+	models.ItemStatusShipped:   {},
+	models.ItemStatusCompleted: {},
+	models.ItemStatusCancelled: {models.ItemStatusListed},
+}
+
+// tradingItemStatuses は取引が進行中のステータスです
+var tradingItemStatuses = []string{
+	models.ItemStatusReserved,
+	models.ItemStatusSold,
+	models.ItemStatusShipped,
+}
+
+// soldOutStatuses は購入済みとして扱うステータスです
+var soldOutStatuses = []string{
+	models.ItemStatusSold,
+	models.ItemStatusShipped,
+	models.ItemStatusCompleted,
+}
+
+// publicItemStatuses は出品者以外にも公開するステータスです
+var publicItemStatuses = []string{
+	models.ItemStatusListed,
+	models.ItemStatusReserved,
+	models.ItemStatusSold,
+	models.ItemStatusShipped,
+	models.ItemStatusCompleted,
+}
+
+// PublicItemStatuses は出品者以外にも公開するステータスの一覧を返します
+func PublicItemStatuses() []string {
+	return slices.Clone(publicItemStatuses)
+}
+
+func canTransitionItemStatus(from string, to string) bool {
+	return slices.Contains(itemStatusTransitions[from], to)
+}
+
+// resolveItemStatuses は status と soldOut の絞り込み条件を組み合わせてステータスの一覧にします
+// どちらも指定されていない場合は nil を返し、両方の条件を満たすステータスがない場合は空のスライスを返します
+func resolveItemStatuses(statuses []string, soldOut *bool) []string {
+	if soldOut == nil {
+		if len(statuses) == 0 {
+			return nil
+		}
+		return statuses
+	}
+	if len(statuses) == 0 {
+		for status := range itemStatusTransitions {
+			statuses = append(statuses, status)
+		}
+		slices.Sort(statuses)
+	}
+
+	resolved := []string{}
+	for _, status := range statuses {
+		if slices.Contains(soldOutStatuses, status) == *soldOut {
+			resolved = append(resolved, status)
+		}
+	}
+	return resolved
+}
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"gin-fleamarket/dto"
+	"gin-fleamarket/models"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+const (
+	maxImportRows   = 1000
+	exportBatchSize = 100
+)
+
+var exportCSVHeader = []string{"id", "name", "price", "description", "category", "status", "created_at"}
+
+type IItemTransferService interface {
+	Import(userId uint, r io.Reader) (*dto.ImportItemsOutput, error)
+	Export(userId uint, format string, w io.Writer) error
+}
+
+// ItemTransferService は出品のCSV一括登録とCSV/JSONでのエクスポートを行います
+type ItemTransferService struct {
+	itemService IItemService
+}
+
+func NewItemTransferService(itemService IItemService) IItemTransferService {
+	return &ItemTransferService{itemService: itemService}
+}
+
+// Import はヘッダー付きのCSVを1行ずつ出品します
+// name と price は必須の列で、description, category, status は任意です。失敗した行があっても他の行は登録されます
+// エクスポートしたCSVをそのまま取り込めるように、出品者が指定できないステータスの行は下書きとして登録します
+func (s *ItemTransferService) Import(userId uint, r io.Reader) (*dto.ImportItemsOutput, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.New("Invalid CSV")
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.TrimPrefix(name, "\ufeff")
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, errors.New("Invalid CSV header")
+	}
+	if _, ok := columns["price"]; !ok {
+		return nil, errors.New("Invalid CSV header")
+	}
+
+	var records [][]string
+	var lines []int
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.New("Invalid CSV")
+		}
+		if len(records) == maxImportRows {
+			return nil, errors.New("Too many rows")
+		}
+		line, _ := reader.FieldPos(0)
+		records = append(records, record)
+		lines = append(lines, line)
+	}
+
+	output := &dto.ImportItemsOutput{Results: []dto.ImportRowResult{}}
+	for i, record := range records {
+		result := dto.ImportRowResult{Row: lines[i]}
+		createdItem, rowErrors := s.importRow(userId, columns, record)
+		if len(rowErrors) > 0 {
+			result.Errors = rowErrors
+			output.Failed++
+		} else {
+			result.ItemID = &createdItem.ID
+			output.Created++
+		}
+		output.Results = append(output.Results, result)
+	}
+	return output, nil
+}
+
+func (s *ItemTransferService) importRow(userId uint, columns map[string]int, record []string) (*models.Item, []string) {
+	field := func(name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var rowErrors []string
+	input := dto.CreateItemInput{
+		Name:        field("name"),
+		Description: field("description"),
+		Status:      importStatus(field("status")),
+	}
+	if price := field("price"); price != "" {
+		p, err := strconv.ParseUint(price, 10, 32)
+		if err != nil {
+			rowErrors = append(rowErrors, "Invalid price")
+		}
+		input.Price = uint(p)
+	}
+	if category := field("category"); category != "" {
+		categoryId, err := strconv.ParseUint(category, 10, 64)
+		if err != nil {
+			rowErrors = append(rowErrors, "Invalid category")
+		} else {
+			c := uint(categoryId)
+			input.CategoryID = &c
+		}
+	}
+	if len(rowErrors) > 0 {
+		return nil, rowErrors
+	}
+
+	// API からの出品と同じバリデーションルールを適用する
+	if err := binding.Validator.ValidateStruct(&input); err != nil {
+		return nil, strings.Split(err.Error(), "\n")
+	}
+	createdItem, err := s.itemService.Create(input, userId)
+	if err != nil {
+		return nil, []string{err.Error()}
+	}
+	return createdItem, nil
+}
+
+// importStatus は取引や出品の終了で決まるステータスを下書きに置き換えます。不明なステータスはそのまま返してバリデーションで拒否します
+func importStatus(status string) string {
+	if _, ok := itemStatusTransitions[status]; ok && status != models.ItemStatusListed {
+		return models.ItemStatusDraft
+	}
+	return status
+}
+
+// Export はログインユーザーの全アイテムを新着順に w へ書き出します
+func (s *ItemTransferService) Export(userId uint, format string, w io.Writer) error {
+	if format == "json" {
+		return s.exportJSON(userId, w)
+	}
+	return s.exportCSV(userId, w)
+}
+
+func (s *ItemTransferService) exportCSV(userId uint, w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(exportCSVHeader); err != nil {
+		return err
+	}
+	err := s.eachItem(userId, func(row dto.ExportItemRow) error {
+		category := ""
+		if row.CategoryID != nil {
+			category = strconv.FormatUint(uint64(*row.CategoryID), 10)
+		}
+		return writer.Write([]string{
+			strconv.FormatUint(uint64(row.ID), 10),
+			row.Name,
+			strconv.FormatUint(uint64(row.Price), 10),
+			row.Description,
+			category,
+			row.Status,
+			row.CreatedAt.Format(time.RFC3339),
+		})
+	}, writer.Flush)
+	if err != nil {
+		return err
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func (s *ItemTransferService) exportJSON(userId uint, w io.Writer) error {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	first := true
+	err := s.eachItem(userId, func(row dto.ExportItemRow) error {
+		b, err := json.Marshal(row)
+		if err != nil {
+			return err
+		}
+		if !first {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		first = false
+		_, err = w.Write(b)
+		return err
+	}, func() {})
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "]")
+	return err
+}
+
+// eachItem はアイテムをページ単位で読み込みながら fn を呼び出し、ページごとに flush を呼び出します
+func (s *ItemTransferService) eachItem(userId uint, fn func(row dto.ExportItemRow) error, flush func()) error {
+	query := dto.FindItemsQuery{Limit: exportBatchSize}
+	for {
+		page, err := s.itemService.FindMine(userId, query)
+		if err != nil {
+			return err
+		}
+		for _, item := range page.Items {
+			row := dto.ExportItemRow{
+				ID:          item.ID,
+				Name:        item.Name,
+				Price:       item.Price,
+				Description: item.Description,
+				CategoryID:  item.CategoryID,
+				Status:      item.Status,
+				CreatedAt:   item.CreatedAt,
+			}
+			if err := fn(row); err != nil {
+				return err
+			}
+		}
+		flush()
+		if page.NextCursor == nil {
+			return nil
+		}
+		query.Cursor = *page.NextCursor
+	}
+}
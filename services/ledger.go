@@ -0,0 +1,77 @@
+package services
+
+import (
+	"errors"
+	"gin-fleamarket/models"
+	"gin-fleamarket/repositories"
+)
+
+type ledgerLine struct {
+	account string
+	userId  *uint
+	amount  int64
+}
+
+// postLedger は1つの取引の仕訳をまとめて記録します。金額の合計が0にならない仕訳は記録しません
+func postLedger(tx repositories.IWalletRepository, entryType string, orderId *uint, payoutId *uint, lines ...ledgerLine) error {
+	transactionId, err := randomHex(16)
+	if err != nil {
+		return err
+	}
+
+	var sum int64
+	entries := make([]models.LedgerEntry, 0, len(lines))
+	for _, line := range lines {
+		sum += line.amount
+		entries = append(entries, models.LedgerEntry{
+			TransactionID: transactionId,
+			Account:       line.account,
+			UserID:        line.userId,
+			Amount:        line.amount,
+			Type:          entryType,
+			OrderID:       orderId,
+			PayoutID:      payoutId,
+		})
+	}
+	if sum != 0 {
+		return errors.New("Unbalanced ledger entries")
+	}
+	return tx.CreateEntries(entries)
+}
+
+// postPayment は購入者からの支払いをプラットフォームの預かり金に入れます
+func postPayment(tx repositories.IWalletRepository, order models.Order) error {
+	amount := int64(order.Price)
+	return postLedger(tx, models.LedgerEntryTypePayment, &order.ID, nil,
+		ledgerLine{account: models.LedgerAccountExternal, amount: -amount},
+		ledgerLine{account: models.LedgerAccountPlatformEscrow, amount: amount},
+	)
+}
+
+// postSale は預かり金を出品者の売上にし、そこから販売手数料を差し引きます
+func postSale(tx repositories.IWalletRepository, order models.Order, feePercent uint) error {
+	amount := int64(order.Price)
+	fee := amount * int64(feePercent) / 100
+	if err := postLedger(tx, models.LedgerEntryTypeSale, &order.ID, nil,
+		ledgerLine{account: models.LedgerAccountPlatformEscrow, amount: -amount},
+		ledgerLine{account: models.LedgerAccountUserAvailable, userId: &order.SellerID, amount: amount},
+	); err != nil {
+		return err
+	}
+	if fee == 0 {
+		return nil
+	}
+	return postLedger(tx, models.LedgerEntryTypeFee, &order.ID, nil,
+		ledgerLine{account: models.LedgerAccountUserAvailable, userId: &order.SellerID, amount: -fee},
+		ledgerLine{account: models.LedgerAccountPlatformFees, amount: fee},
+	)
+}
+
+// postRefund は預かり金を購入者に返します
+func postRefund(tx repositories.IWalletRepository, order models.Order) error {
+	amount := int64(order.Price)
+	return postLedger(tx, models.LedgerEntryTypeRefund, &order.ID, nil,
+		ledgerLine{account: models.LedgerAccountPlatformEscrow, amount: -amount},
+		ledgerLine{account: models.LedgerAccountExternal, amount: amount},
+	)
+}
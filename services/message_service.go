@@ -0,0 +1,97 @@
+package services
+
+import (
+	"errors"
+	"gin-fleamarket/dto"
+	"gin-fleamarket/models"
+	"gin-fleamarket/repositories"
+	"time"
+)
+
+type IMessageService interface {
+	Send(orderId uint, userId uint, input dto.SendMessageInput) (*models.OrderMessage, error)
+	FindByOrder(orderId uint, userId uint, query dto.PageQuery) (*dto.MessagePage, error)
+	MarkRead(orderId uint, userId uint) (int64, error)
+	CountUnread(userId uint) (*dto.UnreadMessagesOutput, error)
+}
+
+// MessageService は注文ごとの購入者と出品者のメッセージのやり取りを扱います
+// 取引の当事者以外には、注文が存在しないものとして扱います
+type MessageService struct {
+	repository      repositories.IMessageRepository
+	orderRepository repositories.IOrderRepository
+}
+
+func NewMessageService(repository repositories.IMessageRepository, orderRepository repositories.IOrderRepository) IMessageService {
+	return &MessageService{repository: repository, orderRepository: orderRepository}
+}
+
+const defaultMessagePageLimit = 20
+
+func (s *MessageService) Send(orderId uint, userId uint, input dto.SendMessageInput) (*models.OrderMessage, error) {
+	order, err := s.findOrder(orderId, userId)
+	if err != nil {
+		return nil, err
+	}
+	recipientId := order.SellerID
+	if userId == order.SellerID {
+		recipientId = order.BuyerID
+	}
+	return s.repository.Create(models.OrderMessage{
+		OrderID:     order.ID,
+		SenderID:    userId,
+		RecipientID: recipientId,
+		Body:        input.Body,
+	})
+}
+
+func (s *MessageService) FindByOrder(orderId uint, userId uint, query dto.PageQuery) (*dto.MessagePage, error) {
+	if _, err := s.findOrder(orderId, userId); err != nil {
+		return nil, err
+	}
+	limit := query.Limit
+	if limit == 0 {
+		limit = defaultMessagePageLimit
+	}
+	messages, total, err := s.repository.FindByOrder(orderId, limit, query.Offset)
+	if err != nil {
+		return nil, err
+	}
+	unread, err := s.repository.CountUnreadByOrder(orderId, userId)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.MessagePage{Messages: *messages, Total: total, Unread: unread}, nil
+}
+
+// MarkRead はログインユーザーが受信したメッセージをすべて既読にします。既読にした日時は送信者にも公開されます
+func (s *MessageService) MarkRead(orderId uint, userId uint) (int64, error) {
+	if _, err := s.findOrder(orderId, userId); err != nil {
+		return 0, err
+	}
+	return s.repository.MarkRead(orderId, userId, time.Now())
+}
+
+func (s *MessageService) CountUnread(userId uint) (*dto.UnreadMessagesOutput, error) {
+	counts, err := s.repository.CountUnread(userId)
+	if err != nil {
+		return nil, err
+	}
+	output := &dto.UnreadMessagesOutput{Orders: []dto.UnreadCount{}}
+	for _, count := range *counts {
+		output.Orders = append(output.Orders, dto.UnreadCount{OrderID: count.OrderID, Count: count.Count})
+		output.Total += count.Count
+	}
+	return output, nil
+}
+
+func (s *MessageService) findOrder(orderId uint, userId uint) (*models.Order, error) {
+	order, err := s.orderRepository.FindById(orderId)
+	if err != nil {
+		return nil, err
+	}
+	if order.BuyerID != userId && order.SellerID != userId {
+		return nil, errors.New("Order not found")
+	}
+	return order, nil
+}
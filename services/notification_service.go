@@ -0,0 +1,42 @@
+package services
+
+import (
+	"gin-fleamarket/dto"
+	"gin-fleamarket/repositories"
+	"time"
+)
+
+type INotificationService interface {
+	FindByUser(userId uint, query dto.PageQuery) (*dto.NotificationPage, error)
+	MarkRead(userId uint) (int64, error)
+}
+
+type NotificationService struct {
+	repository repositories.INotificationRepository
+}
+
+func NewNotificationService(repository repositories.INotificationRepository) INotificationService {
+	return &NotificationService{repository: repository}
+}
+
+const defaultNotificationPageLimit = 20
+
+func (s *NotificationService) FindByUser(userId uint, query dto.PageQuery) (*dto.NotificationPage, error) {
+	limit := query.Limit
+	if limit == 0 {
+		limit = defaultNotificationPageLimit
+	}
+	notifications, total, err := s.repository.FindByUser(userId, limit, query.Offset)
+	if err != nil {
+		return nil, err
+	}
+	unread, err := s.repository.CountUnread(userId)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.NotificationPage{Notifications: *notifications, Total: total, Unread: unread}, nil
+}
+
+func (s *NotificationService) MarkRead(userId uint) (int64, error) {
+	return s.repository.MarkRead(userId, time.Now())
+}
@@ -0,0 +1,255 @@
+package services
+
+import (
+	"errors"
+	"gin-fleamarket/dto"
+	"gin-fleamarket/models"
+	"gin-fleamarket/repositories"
+	"log"
+	"time"
+)
+
+type IOfferService interface {
+	Create(itemId uint, buyerId uint, input dto.CreateOfferInput) (*models.Offer, error)
+	FindByItem(itemId uint, userId uint) (*[]models.Offer, error)
+	Accept(offerId uint, userId uint) (*models.Offer, error)
+	Reject(offerId uint, userId uint) (*models.Offer, error)
+	Counter(offerId uint, userId uint, input dto.CreateOfferInput) (*models.Offer, error)
+	ExpireAccepted(now time.Time) (int, error)
+	Run(interval time.Duration)
+}
+
+// OfferService は購入希望者と出品者の値下げ交渉を扱います
+// 提案は ttl が過ぎると期限切れになります。期限切れは保存せずに参照時に判定します
+// 承諾された提案は purchaseWindow の間だけアイテムを取り置き、期限を過ぎると ExpireAccepted で取り置きを解除します
+type OfferService struct {
+	repository     repositories.IOrderRepository
+	ttl            time.Duration
+	purchaseWindow time.Duration
+}
+
+func NewOfferService(repository repositories.IOrderRepository, ttl time.Duration, purchaseWindow time.Duration) IOfferService {
+	return &OfferService{repository: repository, ttl: ttl, purchaseWindow: purchaseWindow}
+}
+
+func (s *OfferService) Create(itemId uint, buyerId uint, input dto.CreateOfferInput) (*models.Offer, error) {
+	var offer *models.Offer
+	err := s.repository.Transaction(func(tx repositories.IOrderRepository) error {
+		item, err := s.lockNegotiableItem(tx, itemId)
+		if err != nil {
+			return err
+		}
+		if item.UserID == buyerId {
+			return errors.New("Cannot offer on own item")
+		}
+		if input.Price >= item.Price {
+			return errors.New("Invalid offer price")
+		}
+
+		// 交渉中の提案がある間は新しく提案できない
+		pending, err := tx.Offers().FindLatest(itemId, &buyerId, models.OfferStatusPending)
+		if err != nil && err.Error() != "Offer not found" {
+			return err
+		}
+		if pending != nil && !isOfferExpired(*pending) {
+			return errors.New("Offer already exists")
+		}
+
+		offer, err = tx.Offers().Create(models.Offer{
+			ItemID:     item.ID,
+			BuyerID:    buyerId,
+			SellerID:   item.UserID,
+			ProposedBy: buyerId,
+			Price:      input.Price,
+			Status:     models.OfferStatusPending,
+			ExpiresAt:  time.Now().Add(s.ttl),
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return offer, nil
+}
+
+// FindByItem は出品者にはアイテムへの全ての提案を、それ以外のユーザーには自分の交渉だけを新しい順に返します
+func (s *OfferService) FindByItem(itemId uint, userId uint) (*[]models.Offer, error) {
+	offers, err := s.repository.Offers().FindByItem(itemId, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	visibleOffers := []models.Offer{}
+	for _, offer := range *offers {
+		if offer.BuyerID != userId && offer.SellerID != userId {
+			continue
+		}
+		if isOfferExpired(offer) {
+			offer.Status = models.OfferStatusExpired
+		}
+		visibleOffers = append(visibleOffers, offer)
+	}
+	return &visibleOffers, nil
+}
+
+// Accept は提案を承諾し、合意した価格でアイテムをその購入者のために取り置きます
+func (s *OfferService) Accept(offerId uint, userId uint) (*models.Offer, error) {
+	return s.respond(offerId, userId, func(tx repositories.IOrderRepository, offer *models.Offer) error {
+		item, err := s.lockNegotiableItem(tx, offer.ItemID)
+		if err != nil {
+			return err
+		}
+		// 提案の後に出品価格が下げられていた場合、出品価格より高い価格では取り置かない
+		if offer.Price >= item.Price {
+			return errors.New("Invalid offer price")
+		}
+		if _, err := updateItemStatus(tx, *item, models.ItemStatusReserved); err != nil {
+			return err
+		}
+		offer.Status = models.OfferStatusAccepted
+		offer.ExpiresAt = time.Now().Add(s.purchaseWindow)
+		return nil
+	})
+}
+
+func (s *OfferService) Reject(offerId uint, userId uint) (*models.Offer, error) {
+	return s.respond(offerId, userId, func(tx repositories.IOrderRepository, offer *models.Offer) error {
+		offer.Status = models.OfferStatusRejected
+		return nil
+	})
+}
+
+// Counter は提案を別の価格で提案し返します。新しく作られた提案を返します
+func (s *OfferService) Counter(offerId uint, userId uint, input dto.CreateOfferInput) (*models.Offer, error) {
+	var counterOffer *models.Offer
+	_, err := s.respond(offerId, userId, func(tx repositories.IOrderRepository, offer *models.Offer) error {
+		item, err := s.lockNegotiableItem(tx, offer.ItemID)
+		if err != nil {
+			return err
+		}
+		if input.Price >= item.Price {
+			return errors.New("Invalid offer price")
+		}
+		offer.Status = models.OfferStatusCountered
+
+		counterOffer, err = tx.Offers().Create(models.Offer{
+			ItemID:     offer.ItemID,
+			BuyerID:    offer.BuyerID,
+			SellerID:   offer.SellerID,
+			ProposedBy: userId,
+			ParentID:   &offer.ID,
+			Price:      input.Price,
+			Status:     models.OfferStatusPending,
+			ExpiresAt:  time.Now().Add(s.ttl),
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return counterOffer, nil
+}
+
+// ExpireAccepted は購入の期限を過ぎた承諾済みの提案を期限切れにし、取り置いていたアイテムを出品中に戻します
+func (s *OfferService) ExpireAccepted(now time.Time) (int, error) {
+	offers, err := s.repository.Offers().FindExpiredAccepted(now)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, offer := range *offers {
+		err := s.repository.Transaction(func(tx repositories.IOrderRepository) error {
+			lockedOffer, err := tx.Offers().LockOffer(offer.ID)
+			if err != nil {
+				return err
+			}
+			// 一覧を取得した後に購入された
+			if lockedOffer.Status != models.OfferStatusAccepted {
+				return nil
+			}
+			lockedOffer.Status = models.OfferStatusExpired
+			if _, err := tx.Offers().Update(*lockedOffer); err != nil {
+				return err
+			}
+			return syncItemStatus(tx, lockedOffer.ItemID, models.ItemStatusReserved, models.ItemStatusListed)
+		})
+		if err != nil {
+			return expired, err
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+// Run は interval ごとに ExpireAccepted を実行し続けます。goroutine で呼び出してください
+func (s *OfferService) Run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		expired, err := s.ExpireAccepted(now)
+		if err != nil {
+			log.Printf("Failed to expire accepted offers: %v", err)
+			continue
+		}
+		if expired > 0 {
+			log.Printf("Expired %d accepted offers", expired)
+		}
+	}
+}
+
+// respond は提案をロックし、提案された側による返答として fn で変更を加えます
+// 交渉の当事者以外には提案が存在しないものとして扱います
+func (s *OfferService) respond(offerId uint, userId uint, fn func(tx repositories.IOrderRepository, offer *models.Offer) error) (*models.Offer, error) {
+	var updatedOffer *models.Offer
+	err := s.repository.Transaction(func(tx repositories.IOrderRepository) error {
+		offer, err := tx.Offers().LockOffer(offerId)
+		if err != nil {
+			return err
+		}
+		if offer.BuyerID != userId && offer.SellerID != userId {
+			return errors.New("Offer not found")
+		}
+		if offer.ProposedBy == userId {
+			return errors.New("Forbidden")
+		}
+		if offer.Status != models.OfferStatusPending {
+			return errors.New("Invalid offer status")
+		}
+		if isOfferExpired(*offer) {
+			return errors.New("Offer expired")
+		}
+
+		if err := fn(tx, offer); err != nil {
+			return err
+		}
+		updatedOffer, err = tx.Offers().Update(*offer)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updatedOffer, nil
+}
+
+// lockNegotiableItem はアイテムをロックし、交渉できる出品中の状態であることを確認します。オークション形式の出品は交渉できません
+func (s *OfferService) lockNegotiableItem(tx repositories.IOrderRepository, itemId uint) (*models.Item, error) {
+	item, err := tx.LockItem(itemId)
+	if err != nil {
+		return nil, err
+	}
+	if item.Status == models.ItemStatusDraft || item.Status == models.ItemStatusCancelled {
+		return nil, errors.New("Item not found")
+	}
+	if item.Status != models.ItemStatusListed || item.ListingType == models.ListingTypeAuction {
+		return nil, errors.New("Item not available")
+	}
+	return item, nil
+}
+
+func isOfferExpired(offer models.Offer) bool {
+	if offer.Status != models.OfferStatusPending && offer.Status != models.OfferStatusAccepted {
+		return false
+	}
+	return time.Now().After(offer.ExpiresAt)
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"gin-fleamarket/dto"
+	"gin-fleamarket/infra"
+	"gin-fleamarket/models"
+	"gin-fleamarket/repositories"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gorm.io/gorm"
+	"gotest.tools/v3/assert"
+)
+
+func setupOfferServiceTest() (IOfferService, IOrderService, *gorm.DB) {
+	// テスト環境の読み込み
+	if err := godotenv.Load("../.env.test"); err != nil {
+		os.Setenv("ENV", "test")
+	}
+
+	db := infra.SetupDB()
+	db.AutoMigrate(&models.Item{}, &models.Offer{}, &models.Order{}, &models.OrderEvent{})
+	repository := repositories.NewOrderRepository(db)
+	return NewOfferService(repository, time.Hour, time.Hour), NewOrderService(repository), db
+}
+
+func TestExpireAccepted(t *testing.T) {
+	offerService, orderService, db := setupOfferServiceTest()
+
+	item := models.Item{Name: "商品", Price: 1000, Status: models.ItemStatusListed, UserID: 1}
+	db.Create(&item)
+	offer, err := offerService.Create(item.ID, 2, dto.CreateOfferInput{Price: 800})
+	assert.NilError(t, err)
+	offer, err = offerService.Accept(offer.ID, 1)
+	assert.NilError(t, err)
+
+	// テストケース1: 購入の期限までは取り置かれる
+	expired, err := offerService.ExpireAccepted(time.Now())
+	assert.NilError(t, err)
+	assert.Equal(t, 0, expired)
+	db.First(&item, item.ID)
+	assert.Equal(t, models.ItemStatusReserved, item.Status)
+
+	// テストケース2: 期限を過ぎると取り置きが解除され、誰でも出品価格で購入できる
+	expired, err = offerService.ExpireAccepted(offer.ExpiresAt.Add(time.Second))
+	assert.NilError(t, err)
+	assert.Equal(t, 1, expired)
+	db.First(&item, item.ID)
+	assert.Equal(t, models.ItemStatusListed, item.Status)
+	db.First(offer, offer.ID)
+	assert.Equal(t, models.OfferStatusExpired, offer.Status)
+
+	order, err := orderService.Purchase(item.ID, 3)
+	assert.NilError(t, err)
+	assert.Equal(t, uint(1000), order.Price)
+}
+
+func TestAcceptAfterPriceDrop(t *testing.T) {
+	offerService, _, db := setupOfferServiceTest()
+
+	item := models.Item{Name: "商品", Price: 1000, Status: models.ItemStatusListed, UserID: 1}
+	db.Create(&item)
+	offer, err := offerService.Create(item.ID, 2, dto.CreateOfferInput{Price: 800})
+	assert.NilError(t, err)
+
+	// テストケース1: 出品価格が提案額以下に下げられた後は承諾できない
+	db.Model(&item).Update("price", 800)
+	_, err = offerService.Accept(offer.ID, 1)
+	assert.Error(t, err, "Invalid offer price")
+	db.First(&item, item.ID)
+	assert.Equal(t, models.ItemStatusListed, item.Status)
+	db.First(offer, offer.ID)
+	assert.Equal(t, models.OfferStatusPending, offer.Status)
+
+	// テストケース2: 出品価格が提案額より高ければ承諾できる
+	db.Model(&item).Update("price", 900)
+	offer, err = offerService.Accept(offer.ID, 1)
+	assert.NilError(t, err)
+	assert.Equal(t, models.OfferStatusAccepted, offer.Status)
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"errors"
+	"gin-fleamarket/models"
+	"gin-fleamarket/repositories"
+)
+
+type IOrderService interface {
+	FindById(orderId uint, userId uint) (*models.Order, error)
+	Purchase(itemId uint, buyerId uint) (*models.Order, error)
+}
+
+type OrderService struct {
+	repository repositories.IOrderRepository
+}
+
+func NewOrderService(repository repositories.IOrderRepository) IOrderService {
+	return &OrderService{repository: repository}
+}
+
+// FindById は購入者か出品者の場合のみ注文を返します
+func (s *OrderService) FindById(orderId uint, userId uint) (*models.Order, error) {
+	order, err := s.repository.FindById(orderId)
+	if err != nil {
+		return nil, err
+	}
+	if order.BuyerID != userId && order.SellerID != userId {
+		return nil, errors.New("Order not found")
+	}
+	return order, nil
+}
+
+// Purchase は注文の作成とアイテムの売り切れへの変更を1つのトランザクションで行います
+// アイテムの行をロックしてから状態を確認するため、同時に購入されても成功するのは1人だけです
+// 値下げ交渉が成立して取り置かれているアイテムは、その購入者だけが合意した価格で購入できます
+func (s *OrderService) Purchase(itemId uint, buyerId uint) (*models.Order, error) {
+	var order *models.Order
+	err := s.repository.Transaction(func(tx repositories.IOrderRepository) error {
+		item, err := tx.LockItem(itemId)
+		if err != nil {
+			return err
+		}
+		if item.UserID == buyerId {
+			return errors.New("Cannot purchase own item")
+		}
+
+		// オークション形式の出品は落札でのみ購入できる
+		if item.ListingType == models.ListingTypeAuction {
+			return errors.New("Item not available")
+		}
+
+		price := item.Price
+		var offerId *uint
+		switch item.Status {
+		case models.ItemStatusListed:
+		case models.ItemStatusReserved:
+			offer, err := tx.Offers().FindLatest(item.ID, nil, models.OfferStatusAccepted)
+			if err != nil {
+				if err.Error() == "Offer not found" {
+					return errors.New("Item not available")
+				}
+				return err
+			}
+			// 購入の期限を過ぎた取り置きは ExpireAccepted で解除されるまで誰も購入できない
+			if offer.BuyerID != buyerId || isOfferExpired(*offer) {
+				return errors.New("Item not available")
+			}
+			offer.Status = models.OfferStatusPurchased
+			if _, err := tx.Offers().Update(*offer); err != nil {
+				return err
+			}
+			price = offer.Price
+			offerId = &offer.ID
+		case models.ItemStatusDraft, models.ItemStatusCancelled:
+			return errors.New("Item not found")
+		default:
+			return errors.New("Item not available")
+		}
+
+		if _, err := updateItemStatus(tx, *item, models.ItemStatusSold); err != nil {
+			if err.Error() == "Version conflict" {
+				return errors.New("Item not available")
+			}
+			return err
+		}
+		order, err = tx.Create(models.Order{
+			ItemID:   item.ID,
+			BuyerID:  buyerId,
+			SellerID: item.UserID,
+			Price:    price,
+			OfferID:  offerId,
+			Status:   models.OrderStatusPendingPayment,
+		})
+		if err != nil {
+			return err
+		}
+		return recordOrderEvent(tx, *order, &buyerId, models.OrderActionPurchase, "", "")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"gin-fleamarket/models"
+	"gin-fleamarket/repositories"
+	"slices"
+)
+
+// orderStatusTransitions は注文ステータスごとに遷移できる次のステータスを定義します
+// capturing と refunding は、決済代行での売上確定・返金を待っている状態です
+var orderStatusTransitions = map[string][]string{
+	models.OrderStatusPendingPayment: {models.OrderStatusPaid, models.OrderStatusFailed, models.OrderStatusRefunding, models.OrderStatusCancelled},
+	models.OrderStatusPaid:           {models.OrderStatusShipped, models.OrderStatusRefunding},
+	models.OrderStatusShipped:        {models.OrderStatusReceived},
+	models.OrderStatusReceived:       {models.OrderStatusCapturing},
+	models.OrderStatusCapturing:      {models.OrderStatusCompleted},
+	models.OrderStatusCompleted:      {},
+	models.OrderStatusRefunding:      {models.OrderStatusCancelled},
+	models.OrderStatusFailed:         {},
+	models.OrderStatusCancelled:      {},
+}
+
+func canTransitionOrderStatus(from string, to string) bool {
+	return slices.Contains(orderStatusTransitions[from], to)
+}
+
+func recordOrderEvent(tx repositories.IOrderRepository, order models.Order, actorId *uint, action string, fromStatus string, note string) error {
+	return tx.CreateEvent(models.OrderEvent{
+		OrderID:    order.ID,
+		ActorID:    actorId,
+		Action:     action,
+		FromStatus: fromStatus,
+		ToStatus:   order.Status,
+		Note:       note,
+	})
+}
+
+// syncItemStatus は注文の進行に合わせてアイテムのステータスを変更します
+// 取引による変更なので、出品者による状態遷移のルールは適用しません。アイテムが削除されている場合は何もしません
+func syncItemStatus(tx repositories.IOrderRepository, itemId uint, from string, to string) error {
+	item, err := tx.LockItem(itemId)
+	if err != nil {
+		if err.Error() == "Item not found" {
+			return nil
+		}
+		return err
+	}
+	if item.Status != from {
+		return nil
+	}
+	_, err = updateItemStatus(tx, *item, to)
+	return err
+}
+
+// updateItemStatus は取引によってアイテムのステータスを変更し、公開しなくなったアイテムを同じトランザクションで検索インデックスから削除します
+func updateItemStatus(tx repositories.IOrderRepository, item models.Item, status string) (*models.Item, error) {
+	updatedItem, err := tx.UpdateItemStatus(item, status)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(publicItemStatuses, status) {
+		if err := tx.Search().DeleteTokens(item.ID); err != nil {
+			return nil, err
+		}
+	}
+	return updatedItem, nil
+}
+
+// releaseItem は成立しなかった注文のアイテムを再び出品中に戻します
+// オークションは締め切られていて購入も入札もできないので、オークション形式の出品は取り消しにします
+func releaseItem(tx repositories.IOrderRepository, itemId uint) error {
+	item, err := tx.LockItem(itemId)
+	if err != nil {
+		if err.Error() == "Item not found" {
+			return nil
+		}
+		return err
+	}
+	if item.ListingType == models.ListingTypeAuction {
+		return syncItemStatus(tx, itemId, models.ItemStatusSold, models.ItemStatusCancelled)
+	}
+	return syncItemStatus(tx, itemId, models.ItemStatusSold, models.ItemStatusListed)
+}
@@ -0,0 +1,267 @@
+package services
+
+import (
+	"errors"
+	"gin-fleamarket/dto"
+	"gin-fleamarket/models"
+	"gin-fleamarket/payment"
+	"gin-fleamarket/repositories"
+	"time"
+)
+
+type IOrderWorkflowService interface {
+	Ship(orderId uint, userId uint, input dto.ShipOrderInput) (*models.Order, error)
+	ConfirmReceipt(orderId uint, userId uint) (*models.Order, error)
+	Rate(orderId uint, userId uint, input dto.RateOrderInput) (*models.Order, error)
+	Cancel(orderId uint, userId uint, input dto.CancelOrderInput) (*models.Order, error)
+	FindEvents(orderId uint, userId uint) (*[]models.OrderEvent, error)
+}
+
+// OrderWorkflowService は支払い後の取引を進めます
+// 発送(出品者) → 受取確認(購入者) → 相互評価 の順に進み、双方の評価が揃うと売上を確定して取引完了になります
+// 支払われた代金は取引完了まで預かり金として台帳に記録し、完了時に販売手数料を差し引いて出品者の売上にします
+// 決済代行での売上確定と返金はロールバックできないので、capturing / refunding への変更をコミットしてから呼び出します
+// 途中で失敗した場合は、同じ操作をやり直すと続きから再開します
+type OrderWorkflowService struct {
+	repository repositories.IOrderRepository
+	provider   payment.IProvider
+	feePercent uint
+}
+
+func NewOrderWorkflowService(repository repositories.IOrderRepository, provider payment.IProvider, feePercent uint) IOrderWorkflowService {
+	return &OrderWorkflowService{repository: repository, provider: provider, feePercent: feePercent}
+}
+
+func (s *OrderWorkflowService) Ship(orderId uint, userId uint, input dto.ShipOrderInput) (*models.Order, error) {
+	return s.transition(orderId, userId, models.OrderActionShip, func(tx repositories.IOrderRepository, order *models.Order) (string, error) {
+		if order.SellerID != userId {
+			return "", errors.New("Forbidden")
+		}
+		if order.Status != models.OrderStatusPaid {
+			return "", errors.New("Invalid order status")
+		}
+		now := time.Now()
+		order.Status = models.OrderStatusShipped
+		order.TrackingNumber = input.TrackingNumber
+		order.ShippedAt = &now
+		if err := syncItemStatus(tx, order.ItemID, models.ItemStatusSold, models.ItemStatusShipped); err != nil {
+			return "", err
+		}
+		return input.TrackingNumber, nil
+	})
+}
+
+func (s *OrderWorkflowService) ConfirmReceipt(orderId uint, userId uint) (*models.Order, error) {
+	return s.transition(orderId, userId, models.OrderActionReceive, func(tx repositories.IOrderRepository, order *models.Order) (string, error) {
+		if order.BuyerID != userId {
+			return "", errors.New("Forbidden")
+		}
+		if order.Status != models.OrderStatusShipped {
+			return "", errors.New("Invalid order status")
+		}
+		now := time.Now()
+		order.Status = models.OrderStatusReceived
+		order.ReceivedAt = &now
+		return "", nil
+	})
+}
+
+// Rate は取引相手を評価します。双方の評価が揃った時点で売上を確定し、取引を完了します
+func (s *OrderWorkflowService) Rate(orderId uint, userId uint, input dto.RateOrderInput) (*models.Order, error) {
+	if order := s.findInterrupted(orderId, userId, models.OrderStatusCapturing); order != nil {
+		return s.complete(*order)
+	}
+	order, err := s.transition(orderId, userId, models.OrderActionRate, func(tx repositories.IOrderRepository, order *models.Order) (string, error) {
+		if order.Status != models.OrderStatusReceived {
+			return "", errors.New("Invalid order status")
+		}
+		ratings, err := tx.FindRatings(order.ID)
+		if err != nil {
+			return "", err
+		}
+		for _, rating := range *ratings {
+			if rating.RaterID == userId {
+				return "", errors.New("Already rated")
+			}
+		}
+
+		rateeId := order.SellerID
+		if userId == order.SellerID {
+			rateeId = order.BuyerID
+		}
+		if _, err := tx.CreateRating(models.OrderRating{
+			OrderID: order.ID,
+			RaterID: userId,
+			RateeID: rateeId,
+			Rating:  input.Rating,
+			Comment: input.Comment,
+		}); err != nil {
+			return "", err
+		}
+
+		if len(*ratings)+1 == 2 {
+			order.Status = models.OrderStatusCapturing
+		}
+		return input.Rating, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != models.OrderStatusCapturing {
+		return order, nil
+	}
+	return s.complete(*order)
+}
+
+// complete は決済代行で売上を確定してから、売上を台帳に記録して取引を完了します
+func (s *OrderWorkflowService) complete(order models.Order) (*models.Order, error) {
+	if _, err := s.provider.Capture(order.PaymentID); err != nil {
+		return nil, err
+	}
+	return s.settle(order.ID, models.OrderStatusCapturing, models.OrderActionComplete, func(tx repositories.IOrderRepository, order *models.Order) error {
+		now := time.Now()
+		order.Status = models.OrderStatusCompleted
+		order.CompletedAt = &now
+		if err := postSale(tx.Wallet(), *order, s.feePercent); err != nil {
+			return err
+		}
+		return syncItemStatus(tx, order.ItemID, models.ItemStatusShipped, models.ItemStatusCompleted)
+	})
+}
+
+// Cancel は発送前の注文を取り消します
+// 支払い前は購入者と出品者のどちらでも、支払い後は出品者のみが取り消せます。与信は取り消され、アイテムは出品中に戻ります
+func (s *OrderWorkflowService) Cancel(orderId uint, userId uint, input dto.CancelOrderInput) (*models.Order, error) {
+	if order := s.findInterrupted(orderId, userId, models.OrderStatusRefunding); order != nil {
+		return s.refund(*order)
+	}
+	order, err := s.transition(orderId, userId, models.OrderActionCancel, func(tx repositories.IOrderRepository, order *models.Order) (string, error) {
+		switch order.Status {
+		case models.OrderStatusPendingPayment:
+		case models.OrderStatusPaid:
+			if order.SellerID != userId {
+				return "", errors.New("Forbidden")
+			}
+		default:
+			return "", errors.New("Invalid order status")
+		}
+
+		if order.Status == models.OrderStatusPaid {
+			if err := postRefund(tx.Wallet(), *order); err != nil {
+				return "", err
+			}
+		}
+		if order.PaymentID != "" {
+			order.Status = models.OrderStatusRefunding
+			return input.Reason, nil
+		}
+		return input.Reason, cancelOrder(tx, order)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != models.OrderStatusRefunding {
+		return order, nil
+	}
+	return s.refund(*order)
+}
+
+// refund は決済代行で与信の取り消しか返金をしてから、注文を取り消します
+func (s *OrderWorkflowService) refund(order models.Order) (*models.Order, error) {
+	if _, err := s.provider.Refund(order.PaymentID); err != nil {
+		return nil, err
+	}
+	return s.settle(order.ID, models.OrderStatusRefunding, models.OrderActionRefund, cancelOrder)
+}
+
+func cancelOrder(tx repositories.IOrderRepository, order *models.Order) error {
+	now := time.Now()
+	order.Status = models.OrderStatusCancelled
+	order.CancelledAt = &now
+	return releaseItem(tx, order.ItemID)
+}
+
+// findInterrupted は決済代行の呼び出しの前後で中断された、status の注文を返します。取引の当事者でない場合は nil を返します
+func (s *OrderWorkflowService) findInterrupted(orderId uint, userId uint, status string) *models.Order {
+	order, err := s.repository.FindById(orderId)
+	if err != nil || order.Status != status || (order.BuyerID != userId && order.SellerID != userId) {
+		return nil
+	}
+	return order
+}
+
+// settle は決済代行の処理が済んだ注文を from から次のステータスに進め、システムの操作として履歴に記録します
+// 同時に再開された場合など、既に from でなくなっている場合は何もせずに注文を返します
+func (s *OrderWorkflowService) settle(orderId uint, from string, action string, fn func(tx repositories.IOrderRepository, order *models.Order) error) (*models.Order, error) {
+	var updatedOrder *models.Order
+	err := s.repository.Transaction(func(tx repositories.IOrderRepository) error {
+		order, err := tx.LockOrder(orderId)
+		if err != nil {
+			return err
+		}
+		if order.Status != from {
+			updatedOrder = order
+			return nil
+		}
+		if err := fn(tx, order); err != nil {
+			return err
+		}
+		if !canTransitionOrderStatus(from, order.Status) {
+			return errors.New("Invalid order status")
+		}
+		updatedOrder, err = tx.Update(*order)
+		if err != nil {
+			return err
+		}
+		return recordOrderEvent(tx, *updatedOrder, nil, action, from, "")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updatedOrder, nil
+}
+
+func (s *OrderWorkflowService) FindEvents(orderId uint, userId uint) (*[]models.OrderEvent, error) {
+	order, err := s.repository.FindById(orderId)
+	if err != nil {
+		return nil, err
+	}
+	if order.BuyerID != userId && order.SellerID != userId {
+		return nil, errors.New("Order not found")
+	}
+	return s.repository.FindEvents(orderId)
+}
+
+// transition は注文をロックしてから fn で変更を加え、変更内容を操作した人と一緒に履歴へ記録します
+// fn は履歴に残すメモを返します。取引の当事者以外は注文が存在しないものとして扱います
+func (s *OrderWorkflowService) transition(orderId uint, userId uint, action string, fn func(tx repositories.IOrderRepository, order *models.Order) (string, error)) (*models.Order, error) {
+	var updatedOrder *models.Order
+	err := s.repository.Transaction(func(tx repositories.IOrderRepository) error {
+		order, err := tx.LockOrder(orderId)
+		if err != nil {
+			return err
+		}
+		if order.BuyerID != userId && order.SellerID != userId {
+			return errors.New("Order not found")
+		}
+
+		fromStatus := order.Status
+		note, err := fn(tx, order)
+		if err != nil {
+			return err
+		}
+		if order.Status != fromStatus && !canTransitionOrderStatus(fromStatus, order.Status) {
+			return errors.New("Invalid order status")
+		}
+
+		updatedOrder, err = tx.Update(*order)
+		if err != nil {
+			return err
+		}
+		return recordOrderEvent(tx, *updatedOrder, &userId, action, fromStatus, note)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updatedOrder, nil
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"errors"
+	"gin-fleamarket/dto"
+	"gin-fleamarket/infra"
+	"gin-fleamarket/models"
+	"gin-fleamarket/payment"
+	"gin-fleamarket/repositories"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/joho/godotenv"
+	"gorm.io/gorm"
+	"gotest.tools/v3/assert"
+)
+
+// flakyProvider は指定した回数だけ売上確定と返金を失敗させます
+type flakyProvider struct {
+	payment.IProvider
+	failures int
+}
+
+func (p *flakyProvider) Capture(paymentId string) (*payment.Payment, error) {
+	if p.failures > 0 {
+		p.failures--
+		return nil, errors.New("Provider unavailable")
+	}
+	return p.IProvider.Capture(paymentId)
+}
+
+func (p *flakyProvider) Refund(paymentId string) (*payment.Payment, error) {
+	if p.failures > 0 {
+		p.failures--
+		return nil, errors.New("Provider unavailable")
+	}
+	return p.IProvider.Refund(paymentId)
+}
+
+func setupOrderWorkflowServiceTest() (IOrderWorkflowService, *flakyProvider, *gorm.DB) {
+	// テスト環境の読み込み
+	if err := godotenv.Load("../.env.test"); err != nil {
+		os.Setenv("ENV", "test")
+	}
+
+	db := infra.SetupDB()
+	db.AutoMigrate(&models.User{}, &models.Item{}, &models.Order{}, &models.OrderEvent{}, &models.OrderRating{}, &models.LedgerEntry{}, &models.ItemSearchToken{})
+	db.Create(&[]models.User{{Email: "seller@example.com"}, {Email: "buyer@example.com"}})
+
+	provider := &flakyProvider{IProvider: payment.NewFakeProvider("secret", "")}
+	return NewOrderWorkflowService(repositories.NewOrderRepository(db), provider, 10), provider, db
+}
+
+// createPaidOrder は出品者1と購入者2の、与信済みで status の注文を作ります
+func createPaidOrder(t *testing.T, db *gorm.DB, provider payment.IProvider, itemStatus string, status string) models.Order {
+	item := models.Item{Name: "商品", Price: 1000, Status: itemStatus, UserID: 1}
+	db.Create(&item)
+	order := models.Order{ItemID: item.ID, BuyerID: 2, SellerID: 1, Price: 1000, Status: status}
+	db.Create(&order)
+	p, err := provider.Authorize(strconv.FormatUint(uint64(order.ID), 10), order.Price)
+	assert.NilError(t, err)
+	order.PaymentID = p.ID
+	db.Save(&order)
+	return order
+}
+
+func TestResumeInterruptedSettlement(t *testing.T) {
+	workflowService, provider, db := setupOrderWorkflowServiceTest()
+
+	// テストケース1: 売上確定に失敗しても評価は残り、評価をやり直すと続きから完了する
+	order := createPaidOrder(t, db, provider, models.ItemStatusShipped, models.OrderStatusReceived)
+	_, err := workflowService.Rate(order.ID, 2, dto.RateOrderInput{Rating: models.RatingGood})
+	assert.NilError(t, err)
+
+	provider.failures = 1
+	_, err = workflowService.Rate(order.ID, 1, dto.RateOrderInput{Rating: models.RatingGood})
+	assert.Error(t, err, "Provider unavailable")
+	db.First(&order, order.ID)
+	assert.Equal(t, models.OrderStatusCapturing, order.Status)
+
+	completed, err := workflowService.Rate(order.ID, 1, dto.RateOrderInput{Rating: models.RatingGood})
+	assert.NilError(t, err)
+	assert.Equal(t, models.OrderStatusCompleted, completed.Status)
+	var count int64
+	db.Model(&models.OrderRating{}).Where("order_id = ?", order.ID).Count(&count)
+	assert.Equal(t, int64(2), count)
+	db.Model(&models.LedgerEntry{}).Where("order_id = ? AND type = ?", order.ID, models.LedgerEntryTypeSale).Count(&count)
+	assert.Assert(t, count > 0)
+
+	// テストケース2: 返金に失敗しても、取り消しをやり直すと続きから取り消される
+	order = createPaidOrder(t, db, provider, models.ItemStatusSold, models.OrderStatusPaid)
+	provider.failures = 1
+	_, err = workflowService.Cancel(order.ID, 1, dto.CancelOrderInput{})
+	assert.Error(t, err, "Provider unavailable")
+	db.First(&order, order.ID)
+	assert.Equal(t, models.OrderStatusRefunding, order.Status)
+
+	cancelled, err := workflowService.Cancel(order.ID, 1, dto.CancelOrderInput{})
+	assert.NilError(t, err)
+	assert.Equal(t, models.OrderStatusCancelled, cancelled.Status)
+	var item models.Item
+	db.First(&item, order.ItemID)
+	assert.Equal(t, models.ItemStatusListed, item.Status)
+
+	// テストケース3: 取引の当事者以外は再開できない
+	order = createPaidOrder(t, db, provider, models.ItemStatusSold, models.OrderStatusPaid)
+	provider.failures = 1
+	_, err = workflowService.Cancel(order.ID, 1, dto.CancelOrderInput{})
+	assert.Error(t, err, "Provider unavailable")
+	_, err = workflowService.Cancel(order.ID, 3, dto.CancelOrderInput{})
+	assert.Error(t, err, "Order not found")
+}
+
+func TestCancelAuctionOrder(t *testing.T) {
+	workflowService, _, db := setupOrderWorkflowServiceTest()
+
+	// 落札後に注文が取り消されたら、締め切り済みのオークションは出品取り消しになる
+	item := models.Item{Name: "商品", Price: 1000, Status: models.ItemStatusSold, UserID: 1, ListingType: models.ListingTypeAuction}
+	db.Create(&item)
+	order := models.Order{ItemID: item.ID, BuyerID: 2, SellerID: 1, Price: 1200, Status: models.OrderStatusPendingPayment}
+	db.Create(&order)
+
+	cancelled, err := workflowService.Cancel(order.ID, 2, dto.CancelOrderInput{})
+	assert.NilError(t, err)
+	assert.Equal(t, models.OrderStatusCancelled, cancelled.Status)
+	db.First(&item, item.ID)
+	assert.Equal(t, models.ItemStatusCancelled, item.Status)
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"gin-fleamarket/models"
+	"gin-fleamarket/payment"
+	"gin-fleamarket/repositories"
+	"strconv"
+)
+
+type IPaymentService interface {
+	Pay(orderId uint, userId uint) (*models.Order, error)
+	HandleWebhook(body []byte, signature string) error
+}
+
+type PaymentService struct {
+	repository    repositories.IOrderRepository
+	provider      payment.IProvider
+	webhookSecret string
+}
+
+func NewPaymentService(repository repositories.IOrderRepository, provider payment.IProvider, webhookSecret string) IPaymentService {
+	return &PaymentService{repository: repository, provider: provider, webhookSecret: webhookSecret}
+}
+
+// Pay は支払い待ちの注文の与信を取ります。支払いの結果は Webhook で反映されます
+// 与信は注文IDごとに冪等なので、与信の後で注文に紐付けられなかった場合もやり直せば同じ与信が使われます
+func (s *PaymentService) Pay(orderId uint, userId uint) (*models.Order, error) {
+	targetOrder, err := s.repository.FindById(orderId)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPayable(*targetOrder, userId); err != nil {
+		return nil, err
+	}
+
+	// 与信はロールバックできないので、トランザクションの外で取る
+	p, err := s.provider.Authorize(strconv.FormatUint(uint64(targetOrder.ID), 10), targetOrder.Price)
+	if err != nil {
+		return nil, err
+	}
+
+	var order *models.Order
+	err = s.repository.Transaction(func(tx repositories.IOrderRepository) error {
+		targetOrder, err := tx.LockOrder(orderId)
+		if err != nil {
+			return err
+		}
+		// 先に届いた Webhook で紐付け済みの場合
+		if targetOrder.PaymentID == p.ID {
+			order = targetOrder
+			return nil
+		}
+		if err := checkPayable(*targetOrder, userId); err != nil {
+			return err
+		}
+		targetOrder.PaymentID = p.ID
+		order, err = tx.Update(*targetOrder)
+		return err
+	})
+	if err != nil {
+		if err.Error() == "Invalid order status" {
+			// 与信を取っている間に注文が取り消された場合は、紐付けられなかった与信を取り消しておく
+			s.provider.Refund(p.ID)
+		}
+		return nil, err
+	}
+	return order, nil
+}
+
+func checkPayable(order models.Order, userId uint) error {
+	if order.BuyerID != userId {
+		return errors.New("Order not found")
+	}
+	if order.Status != models.OrderStatusPendingPayment || order.PaymentID != "" {
+		return errors.New("Invalid order status")
+	}
+	return nil
+}
+
+// HandleWebhook は署名を確認したうえで決済イベントを注文に反映します
+// 同じイベントが複数回届いても2回目以降は何もしません
+func (s *PaymentService) HandleWebhook(body []byte, signature string) error {
+	if !payment.VerifySignature(s.webhookSecret, body, signature) {
+		return errors.New("Invalid signature")
+	}
+	var event payment.Event
+	if err := json.Unmarshal(body, &event); err != nil || event.ID == "" || event.PaymentID == "" {
+		return errors.New("Invalid event")
+	}
+	orderId, err := strconv.ParseUint(event.Reference, 10, 64)
+	if err != nil {
+		return errors.New("Invalid event")
+	}
+
+	return s.repository.Transaction(func(tx repositories.IOrderRepository) error {
+		created, err := tx.CreatePaymentEvent(models.PaymentEvent{EventID: event.ID, Type: event.Type, PaymentID: event.PaymentID})
+		if err != nil {
+			return err
+		}
+		if !created {
+			return nil
+		}
+
+		order, err := tx.LockOrder(uint(orderId))
+		if err != nil {
+			return err
+		}
+		if order.Status != models.OrderStatusPendingPayment {
+			return nil
+		}
+		// 与信は注文ごとに1つなので、Pay が紐付ける前に届いたイベントの支払いはこの注文のもの
+		if order.PaymentID == "" {
+			order.PaymentID = event.PaymentID
+		}
+		// 以前の支払いのイベントは無視する
+		if order.PaymentID != event.PaymentID {
+			return nil
+		}
+
+		var action string
+		switch event.Type {
+		case payment.EventPaymentAuthorized:
+			action = models.OrderActionPaymentAuthorized
+			order.Status = models.OrderStatusPaid
+			if err := postPayment(tx.Wallet(), *order); err != nil {
+				return err
+			}
+		case payment.EventPaymentFailed:
+			action = models.OrderActionPaymentFailed
+			order.Status = models.OrderStatusFailed
+			if err := releaseItem(tx, order.ItemID); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+		if _, err := tx.Update(*order); err != nil {
+			return err
+		}
+		return recordOrderEvent(tx, *order, nil, action, models.OrderStatusPendingPayment, event.ID)
+	})
+}
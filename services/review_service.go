@@ -0,0 +1,61 @@
+package services
+
+import (
+	"gin-fleamarket/dto"
+	"gin-fleamarket/models"
+	"gin-fleamarket/repositories"
+)
+
+type IReviewService interface {
+	FindByUser(userId uint, query dto.FindReviewsQuery) (*dto.ReviewPage, error)
+}
+
+type ReviewService struct {
+	repository     repositories.IReviewRepository
+	userRepository repositories.IUserRepository
+}
+
+func NewReviewService(repository repositories.IReviewRepository, userRepository repositories.IUserRepository) IReviewService {
+	return &ReviewService{repository: repository, userRepository: userRepository}
+}
+
+const defaultReviewPageLimit = 20
+
+// FindByUser はユーザーが受け取った評価と、評価の件数の集計を返します
+func (s *ReviewService) FindByUser(userId uint, query dto.FindReviewsQuery) (*dto.ReviewPage, error) {
+	user, err := s.userRepository.FindById(userId)
+	if err != nil {
+		return nil, err
+	}
+	limit := query.Limit
+	if limit == 0 {
+		limit = defaultReviewPageLimit
+	}
+	reviews, total, err := s.repository.FindByRatee(userId, query.Rating, query.Role, limit, query.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := make([]dto.ReviewOutput, 0, len(*reviews))
+	for _, review := range *reviews {
+		outputs = append(outputs, dto.ReviewOutput{
+			ID:        review.ID,
+			OrderID:   review.OrderID,
+			RaterID:   review.RaterID,
+			RaterRole: review.RaterRole,
+			Rating:    review.Rating,
+			Comment:   review.Comment,
+			CreatedAt: review.CreatedAt,
+		})
+	}
+	return &dto.ReviewPage{Reviews: outputs, Total: total, Reputation: reputationOf(*user)}, nil
+}
+
+func reputationOf(user models.User) dto.ReputationSummary {
+	return dto.ReputationSummary{
+		Good:   user.GoodRatingCount,
+		Normal: user.NormalRatingCount,
+		Bad:    user.BadRatingCount,
+		Total:  user.GoodRatingCount + user.NormalRatingCount + user.BadRatingCount,
+	}
+}
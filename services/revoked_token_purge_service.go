@@ -0,0 +1,45 @@
+package services
+
+import (
+	"gin-fleamarket/repositories"
+	"log"
+	"time"
+)
+
+type IRevokedTokenPurgeService interface {
+	PurgeExpired(now time.Time) (int, error)
+	Run(interval time.Duration)
+}
+
+// RevokedTokenPurgeService はログアウトで記録した失効済みトークンのうち、有効期限を過ぎたものを削除します
+type RevokedTokenPurgeService struct {
+	repository repositories.IAuthRepository
+}
+
+func NewRevokedTokenPurgeService(repository repositories.IAuthRepository) IRevokedTokenPurgeService {
+	return &RevokedTokenPurgeService{repository: repository}
+}
+
+func (s *RevokedTokenPurgeService) PurgeExpired(now time.Time) (int, error) {
+	purged, err := s.repository.DeleteExpiredRevokedTokens(now)
+	if err != nil {
+		return 0, err
+	}
+	return int(purged), nil
+}
+
+// Run は interval ごとに PurgeExpired を実行し続けます。goroutine で呼び出してください
+func (s *RevokedTokenPurgeService) Run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		purged, err := s.PurgeExpired(now)
+		if err != nil {
+			log.Printf("Failed to purge revoked tokens: %v", err)
+			continue
+		}
+		if purged > 0 {
+			log.Printf("Purged %d revoked tokens", purged)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"gin-fleamarket/infra"
+	"gin-fleamarket/models"
+	"gin-fleamarket/repositories"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gorm.io/gorm"
+	"gotest.tools/v3/assert"
+)
+
+func setupRevokedTokenPurgeServiceTest() (IRevokedTokenPurgeService, *gorm.DB) {
+	// テスト環境の読み込み
+	if err := godotenv.Load("../.env.test"); err != nil {
+		os.Setenv("ENV", "test")
+	}
+
+	db := infra.SetupDB()
+	db.AutoMigrate(&models.RevokedToken{})
+	return NewRevokedTokenPurgeService(repositories.NewAuthRepository(db)), db
+}
+
+func TestPurgeExpiredRevokedTokens(t *testing.T) {
+	purgeService, db := setupRevokedTokenPurgeServiceTest()
+
+	now := time.Now()
+	db.Create(&models.RevokedToken{JTI: "expired", UserID: 1, ExpiresAt: now.Add(-time.Minute)})
+	db.Create(&models.RevokedToken{JTI: "active", UserID: 1, ExpiresAt: now.Add(time.Hour)})
+
+	// テストケース1: 有効期限を過ぎた記録だけが削除される
+	purged, err := purgeService.PurgeExpired(now)
+	assert.NilError(t, err)
+	assert.Equal(t, 1, purged)
+
+	var jtis []string
+	db.Model(&models.RevokedToken{}).Pluck("jti", &jtis)
+	assert.DeepEqual(t, []string{"active"}, jtis)
+
+	// テストケース2: 削除するものがなければ何もしない
+	purged, err = purgeService.PurgeExpired(now)
+	assert.NilError(t, err)
+	assert.Equal(t, 0, purged)
+}
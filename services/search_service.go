@@ -0,0 +1,125 @@
+package services
+
+import (
+	"gin-fleamarket/models"
+	"gin-fleamarket/repositories"
+	"unicode"
+)
+
+const (
+	nameTokenWeight        = 3
+	descriptionTokenWeight = 1
+)
+
+type ISearchService interface {
+	IndexItem(item models.Item) error
+	RemoveItem(itemId uint) error
+	Search(q string, limit int, offset int) ([]uint, int64, error)
+}
+
+type SearchService struct {
+	repository repositories.ISearchRepository
+}
+
+func NewSearchService(repository repositories.ISearchRepository) ISearchService {
+	return &SearchService{repository: repository}
+}
+
+func (s *SearchService) IndexItem(item models.Item) error {
+	tokens := map[string]uint{}
+	for _, token := range indexTokens(item.Name) {
+		tokens[token] += nameTokenWeight
+	}
+	for _, token := range indexTokens(item.Description) {
+		tokens[token] += descriptionTokenWeight
+	}
+	return s.repository.ReplaceTokens(item.ID, tokens)
+}
+
+func (s *SearchService) RemoveItem(itemId uint) error {
+	return s.repository.DeleteTokens(itemId)
+}
+
+// Search はクエリにマッチした公開中のアイテムIDを関連度の高い順に返します
+func (s *SearchService) Search(q string, limit int, offset int) ([]uint, int64, error) {
+	tokens := queryTokens(q)
+	if len(tokens) == 0 {
+		return []uint{}, 0, nil
+	}
+
+	hits, total, err := s.repository.Search(tokens, publicItemStatuses, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	itemIds := make([]uint, 0, len(hits))
+	for _, hit := range hits {
+		itemIds = append(itemIds, hit.ItemID)
+	}
+	return itemIds, total, nil
+}
+
+// indexTokens はインデックス用にバイグラムと1文字のトークンを返します
+// 1文字だけのクエリでも検索できるようにユニグラムも登録します
+func indexTokens(text string) []string {
+	var tokens []string
+	for _, run := range splitRuns(text) {
+		for i := range run {
+			tokens = append(tokens, string(run[i]))
+			if i+1 < len(run) {
+				tokens = append(tokens, string(run[i:i+2]))
+			}
+		}
+	}
+	return tokens
+}
+
+// queryTokens は検索クエリを重複のないバイグラムに分割します
+func queryTokens(q string) []string {
+	seen := map[string]bool{}
+	var tokens []string
+	add := func(token string) {
+		if !seen[token] {
+			seen[token] = true
+			tokens = append(tokens, token)
+		}
+	}
+	for _, run := range splitRuns(q) {
+		if len(run) == 1 {
+			add(string(run))
+			continue
+		}
+		for i := 0; i+1 < len(run); i++ {
+			add(string(run[i : i+2]))
+		}
+	}
+	return tokens
+}
+
+// splitRuns は文字列を正規化し、記号や空白で区切られた文字の並びに分割します
+func splitRuns(text string) [][]rune {
+	var runs [][]rune
+	var current []rune
+	for _, r := range text {
+		r = normalizeRune(r)
+		if unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.IsMark(r) {
+			current = append(current, r)
+			continue
+		}
+		if len(current) > 0 {
+			runs = append(runs, current)
+			current = nil
+		}
+	}
+	if len(current) > 0 {
+		runs = append(runs, current)
+	}
+	return runs
+}
+
+// normalizeRune は全角英数字を半角に変換し、小文字にそろえます
+func normalizeRune(r rune) rune {
+	if r >= '！' && r <= '～' {
+		r -= '！' - '!'
+	}
+	return unicode.ToLower(r)
+}
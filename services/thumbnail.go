@@ -0,0 +1,49 @@
+package services
+
+import (
+	"image"
+	"image/color"
+)
+
+// resizeToFit は長辺が maxSize 以下になるように画像を縮小します
+// 縮小後の1ピクセルに対応する元画像の領域を平均するため、縮小率が大きくてもジャギーが出にくくなります
+func resizeToFit(src image.Image, maxSize int) image.Image {
+	bounds := src.Bounds()
+	srcWidth, srcHeight := bounds.Dx(), bounds.Dy()
+	if srcWidth <= maxSize && srcHeight <= maxSize {
+		return src
+	}
+
+	dstWidth, dstHeight := maxSize, maxSize
+	if srcWidth > srcHeight {
+		dstHeight = max(1, srcHeight*maxSize/srcWidth)
+	} else {
+		dstWidth = max(1, srcWidth*maxSize/srcHeight)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := 0; y < dstHeight; y++ {
+		y0 := bounds.Min.Y + y*srcHeight/dstHeight
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*srcHeight/dstHeight)
+		for x := 0; x < dstWidth; x++ {
+			x0 := bounds.Min.X + x*srcWidth/dstWidth
+			x1 := max(x0+1, bounds.Min.X+(x+1)*srcWidth/dstWidth)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.SetRGBA64(x, y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(b / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,216 @@
+package services
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"gin-fleamarket/dto"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type verificationKey struct {
+	method jwt.SigningMethod
+	key    any
+}
+
+// TokenKeys はアクセストークンの署名と検証に使う鍵と、発行者・対象者のクレームです
+type TokenKeys struct {
+	method     jwt.SigningMethod
+	kid        string
+	signingKey any
+	// verificationKeys は kid ごとの検証用の鍵です。鍵を入れ替える間は、古い鍵で署名されたトークンも検証できます
+	verificationKeys map[string]verificationKey
+	issuer           string
+	audience         string
+}
+
+// SetupTokenKeys は環境変数から署名用の鍵を読み込みます
+// JWT_PRIVATE_KEY_FILE に RSA または Ed25519 の秘密鍵(PEM)を指定すると、それぞれ RS256 と EdDSA で署名します
+// JWT_VERIFY_KEY_FILES にはカンマ区切りで、検証にだけ使う公開鍵(PEM)を指定できます
+// JWT_PRIVATE_KEY_FILE が未設定の場合は、これまでどおり SECRET_KEY を使った HS256 で署名します
+func SetupTokenKeys() (*TokenKeys, error) {
+	keys := &TokenKeys{
+		verificationKeys: map[string]verificationKey{},
+		issuer:           os.Getenv("JWT_ISSUER"),
+		audience:         os.Getenv("JWT_AUDIENCE"),
+	}
+
+	privateKeyFile := os.Getenv("JWT_PRIVATE_KEY_FILE")
+	if privateKeyFile == "" {
+		secret := []byte(os.Getenv("SECRET_KEY"))
+		keys.method = jwt.SigningMethodHS256
+		keys.signingKey = secret
+		keys.verificationKeys[""] = verificationKey{method: jwt.SigningMethodHS256, key: secret}
+		return keys, nil
+	}
+
+	signingKey, err := readPrivateKey(privateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	publicKey := signingKey.(crypto.Signer).Public()
+	kid, err := keyID(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	keys.method = signingMethodFor(publicKey)
+	keys.kid = kid
+	keys.signingKey = signingKey
+	keys.verificationKeys[kid] = verificationKey{method: keys.method, key: publicKey}
+
+	for _, file := range strings.Split(os.Getenv("JWT_VERIFY_KEY_FILES"), ",") {
+		file = strings.TrimSpace(file)
+		if file == "" {
+			continue
+		}
+		publicKey, err := readPublicKey(file)
+		if err != nil {
+			return nil, err
+		}
+		kid, err := keyID(publicKey)
+		if err != nil {
+			return nil, err
+		}
+		keys.verificationKeys[kid] = verificationKey{method: signingMethodFor(publicKey), key: publicKey}
+	}
+	return keys, nil
+}
+
+// sign は claims に発行者と対象者を加えて署名します
+func (k *TokenKeys) sign(claims jwt.MapClaims) (string, error) {
+	if k.issuer != "" {
+		claims["iss"] = k.issuer
+	}
+	if k.audience != "" {
+		claims["aud"] = k.audience
+	}
+	token := jwt.NewWithClaims(k.method, claims)
+	if k.kid != "" {
+		token.Header["kid"] = k.kid
+	}
+	return token.SignedString(k.signingKey)
+}
+
+// parse は kid ヘッダーで選んだ鍵で署名を検証し、発行者と対象者が設定されている場合はそれらも検証します
+func (k *TokenKeys) parse(tokenString string) (*jwt.Token, error) {
+	var methods []string
+	for _, key := range k.verificationKeys {
+		methods = append(methods, key.method.Alg())
+	}
+	options := []jwt.ParserOption{jwt.WithValidMethods(methods)}
+	if k.issuer != "" {
+		options = append(options, jwt.WithIssuer(k.issuer))
+	}
+	if k.audience != "" {
+		options = append(options, jwt.WithAudience(k.audience))
+	}
+
+	return jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := k.verificationKeys[kid]
+		if !ok {
+			return nil, fmt.Errorf("Unknown key id: %v", kid)
+		}
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
+		}
+		return key.key, nil
+	}, options...)
+}
+
+// jwks は検証用の公開鍵を JWK Set として返します。HS256 の共有鍵は公開しません
+func (k *TokenKeys) jwks() dto.JWKSetOutput {
+	output := dto.JWKSetOutput{Keys: []dto.JWKOutput{}}
+	for kid, key := range k.verificationKeys {
+		jwk := dto.JWKOutput{Kid: kid, Alg: key.method.Alg(), Use: "sig"}
+		switch publicKey := key.key.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+		default:
+			continue
+		}
+		output.Keys = append(output.Keys, jwk)
+	}
+	return output
+}
+
+func readPrivateKey(file string) (any, error) {
+	block, err := readPEM(file)
+	if err != nil {
+		return nil, err
+	}
+	if block.Type == "RSA PRIVATE KEY" {
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	switch key.(type) {
+	case *rsa.PrivateKey, ed25519.PrivateKey:
+		return key, nil
+	}
+	return nil, errors.New("Unsupported key type")
+}
+
+func readPublicKey(file string) (any, error) {
+	block, err := readPEM(file)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	switch key.(type) {
+	case *rsa.PublicKey, ed25519.PublicKey:
+		return key, nil
+	}
+	return nil, errors.New("Unsupported key type")
+}
+
+func readPEM(file string) (*pem.Block, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, fmt.Errorf("Invalid PEM file: %s", file)
+	}
+	return block, nil
+}
+
+func signingMethodFor(publicKey any) jwt.SigningMethod {
+	if _, ok := publicKey.(ed25519.PublicKey); ok {
+		return jwt.SigningMethodEdDSA
+	}
+	return jwt.SigningMethodRS256
+}
+
+// keyID は公開鍵の SHA-256 ハッシュから kid を作ります。同じ鍵からは常に同じ kid になります
+func keyID(publicKey any) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:8]), nil
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gotest.tools/v3/assert"
+)
+
+// writeKeyFiles は秘密鍵と公開鍵を PEM で書き出し、それぞれのファイルパスを返します
+func writeKeyFiles(t *testing.T, name string, privateKey any, publicKey any) (string, string) {
+	dir := t.TempDir()
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	assert.NilError(t, err)
+	publicDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	assert.NilError(t, err)
+
+	privateFile := filepath.Join(dir, name+".pem")
+	publicFile := filepath.Join(dir, name+".pub.pem")
+	assert.NilError(t, os.WriteFile(privateFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0600))
+	assert.NilError(t, os.WriteFile(publicFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0644))
+	return privateFile, publicFile
+}
+
+func TestTokenKeys(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NilError(t, err)
+	rsaPrivateFile, rsaPublicFile := writeKeyFiles(t, "rsa", rsaKey, &rsaKey.PublicKey)
+	edPublicKey, edPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NilError(t, err)
+	edPrivateFile, _ := writeKeyFiles(t, "ed25519", edPrivateKey, edPublicKey)
+
+	t.Setenv("JWT_ISSUER", "https://fleamarket.example.com")
+	t.Setenv("JWT_AUDIENCE", "fleamarket-api")
+
+	// テストケース1: RSA の秘密鍵を指定すると RS256 で署名し、kid ヘッダーを付ける
+	t.Setenv("JWT_PRIVATE_KEY_FILE", rsaPrivateFile)
+	keys, err := SetupTokenKeys()
+	assert.NilError(t, err)
+	rsaToken, err := createToken(keys, 1, "test@example.com", 0)
+	assert.NilError(t, err)
+
+	parsed, err := keys.parse(*rsaToken)
+	assert.NilError(t, err)
+	assert.Equal(t, "RS256", parsed.Method.Alg())
+	rsaKid := parsed.Header["kid"].(string)
+	assert.Assert(t, rsaKid != "")
+	claims := parsed.Claims.(jwt.MapClaims)
+	assert.Equal(t, "https://fleamarket.example.com", claims["iss"])
+	assert.Equal(t, "fleamarket-api", claims["aud"])
+
+	// テストケース2: 鍵を入れ替えても、検証用に残した古い鍵のトークンは検証できる
+	t.Setenv("JWT_PRIVATE_KEY_FILE", edPrivateFile)
+	t.Setenv("JWT_VERIFY_KEY_FILES", rsaPublicFile)
+	keys, err = SetupTokenKeys()
+	assert.NilError(t, err)
+	edToken, err := createToken(keys, 1, "test@example.com", 0)
+	assert.NilError(t, err)
+
+	parsed, err = keys.parse(*edToken)
+	assert.NilError(t, err)
+	assert.Equal(t, "EdDSA", parsed.Method.Alg())
+	_, err = keys.parse(*rsaToken)
+	assert.NilError(t, err)
+
+	// 公開鍵は JWK Set として公開される
+	jwks := keys.jwks()
+	assert.Equal(t, 2, len(jwks.Keys))
+	for _, key := range jwks.Keys {
+		if key.Kid == rsaKid {
+			assert.Equal(t, "RSA", key.Kty)
+			assert.Equal(t, "AQAB", key.E)
+		} else {
+			assert.Equal(t, "OKP", key.Kty)
+			assert.Equal(t, "Ed25519", key.Crv)
+		}
+	}
+
+	// テストケース3: 検証用の鍵から外すと古いトークンは使えない
+	t.Setenv("JWT_VERIFY_KEY_FILES", "")
+	keys, err = SetupTokenKeys()
+	assert.NilError(t, err)
+	_, err = keys.parse(*rsaToken)
+	assert.Assert(t, err != nil)
+
+	// テストケース4: 対象者が異なるトークンは検証に失敗する
+	t.Setenv("JWT_AUDIENCE", "another-api")
+	keys, err = SetupTokenKeys()
+	assert.NilError(t, err)
+	_, err = keys.parse(*edToken)
+	assert.Assert(t, err != nil)
+
+	// テストケース5: 秘密鍵を指定しない場合は HS256 で署名し、共有鍵は公開しない
+	t.Setenv("JWT_PRIVATE_KEY_FILE", "")
+	t.Setenv("JWT_AUDIENCE", "")
+	t.Setenv("SECRET_KEY", "test-secret-key")
+	keys, err = SetupTokenKeys()
+	assert.NilError(t, err)
+	hsToken, err := createToken(keys, 1, "test@example.com", 0)
+	assert.NilError(t, err)
+	parsed, err = keys.parse(*hsToken)
+	assert.NilError(t, err)
+	assert.Equal(t, "HS256", parsed.Method.Alg())
+	assert.Equal(t, 0, len(keys.jwks().Keys))
+
+	// 非対称鍵の設定では HS256 で署名されたトークンを受け付けない
+	t.Setenv("JWT_PRIVATE_KEY_FILE", rsaPrivateFile)
+	keys, err = SetupTokenKeys()
+	assert.NilError(t, err)
+	_, err = keys.parse(*hsToken)
+	assert.Assert(t, err != nil)
+}
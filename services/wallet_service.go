@@ -0,0 +1,136 @@
+package services
+
+import (
+	"errors"
+	"gin-fleamarket/dto"
+	"gin-fleamarket/models"
+	"gin-fleamarket/repositories"
+	"time"
+)
+
+type IWalletService interface {
+	FindWallet(userId uint) (*dto.WalletOutput, error)
+	FindTransactions(userId uint, query dto.PageQuery) (*dto.LedgerEntryPage, error)
+	RequestPayout(userId uint, input dto.CreatePayoutInput) (*models.Payout, error)
+	FindPayouts(userId uint, query dto.FindPayoutsQuery) (*dto.PayoutPage, error)
+	FindAllPayouts(query dto.FindPayoutsQuery) (*dto.PayoutPage, error)
+	MarkPayoutPaid(payoutId uint) (*models.Payout, error)
+}
+
+type WalletService struct {
+	repository repositories.IWalletRepository
+}
+
+func NewWalletService(repository repositories.IWalletRepository) IWalletService {
+	return &WalletService{repository: repository}
+}
+
+const defaultWalletPageLimit = 20
+
+// FindWallet は出金できる残高と、出金申請中で引き出せない残高を返します
+func (s *WalletService) FindWallet(userId uint) (*dto.WalletOutput, error) {
+	available, err := s.repository.Balance(models.LedgerAccountUserAvailable, &userId)
+	if err != nil {
+		return nil, err
+	}
+	locked, err := s.repository.Balance(models.LedgerAccountUserLocked, &userId)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.WalletOutput{Available: available, Locked: locked}, nil
+}
+
+func (s *WalletService) FindTransactions(userId uint, query dto.PageQuery) (*dto.LedgerEntryPage, error) {
+	limit := query.Limit
+	if limit == 0 {
+		limit = defaultWalletPageLimit
+	}
+	entries, total, err := s.repository.FindEntries(userId, limit, query.Offset)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.LedgerEntryPage{Entries: *entries, Total: total}, nil
+}
+
+// RequestPayout は出金を申請し、申請額を管理者が支払い済みにするまで引き出せないようにします
+// 同じユーザーの申請はユーザーの行のロックで直列化するため、残高を超えて申請されることはありません
+func (s *WalletService) RequestPayout(userId uint, input dto.CreatePayoutInput) (*models.Payout, error) {
+	var payout *models.Payout
+	err := s.repository.Transaction(func(tx repositories.IWalletRepository) error {
+		if err := tx.LockUser(userId); err != nil {
+			return err
+		}
+		available, err := tx.Balance(models.LedgerAccountUserAvailable, &userId)
+		if err != nil {
+			return err
+		}
+		amount := int64(input.Amount)
+		if amount > available {
+			return errors.New("Insufficient balance")
+		}
+
+		payout, err = tx.CreatePayout(models.Payout{UserID: userId, Amount: input.Amount, Status: models.PayoutStatusRequested})
+		if err != nil {
+			return err
+		}
+		return postLedger(tx, models.LedgerEntryTypePayoutRequest, nil, &payout.ID,
+			ledgerLine{account: models.LedgerAccountUserAvailable, userId: &userId, amount: -amount},
+			ledgerLine{account: models.LedgerAccountUserLocked, userId: &userId, amount: amount},
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return payout, nil
+}
+
+func (s *WalletService) FindPayouts(userId uint, query dto.FindPayoutsQuery) (*dto.PayoutPage, error) {
+	return s.findPayouts(&userId, query)
+}
+
+func (s *WalletService) FindAllPayouts(query dto.FindPayoutsQuery) (*dto.PayoutPage, error) {
+	return s.findPayouts(nil, query)
+}
+
+func (s *WalletService) findPayouts(userId *uint, query dto.FindPayoutsQuery) (*dto.PayoutPage, error) {
+	limit := query.Limit
+	if limit == 0 {
+		limit = defaultWalletPageLimit
+	}
+	payouts, total, err := s.repository.FindPayouts(userId, query.Status, limit, query.Offset)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.PayoutPage{Payouts: *payouts, Total: total}, nil
+}
+
+// MarkPayoutPaid は振り込みが済んだ出金申請を支払い済みにし、引き出せない残高から差し引きます
+func (s *WalletService) MarkPayoutPaid(payoutId uint) (*models.Payout, error) {
+	var updatedPayout *models.Payout
+	err := s.repository.Transaction(func(tx repositories.IWalletRepository) error {
+		payout, err := tx.LockPayout(payoutId)
+		if err != nil {
+			return err
+		}
+		if payout.Status != models.PayoutStatusRequested {
+			return errors.New("Invalid payout status")
+		}
+
+		now := time.Now()
+		payout.Status = models.PayoutStatusPaid
+		payout.PaidAt = &now
+		updatedPayout, err = tx.UpdatePayout(*payout)
+		if err != nil {
+			return err
+		}
+		amount := int64(payout.Amount)
+		return postLedger(tx, models.LedgerEntryTypePayout, nil, &payout.ID,
+			ledgerLine{account: models.LedgerAccountUserLocked, userId: &payout.UserID, amount: -amount},
+			ledgerLine{account: models.LedgerAccountExternal, amount: amount},
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updatedPayout, nil
+}